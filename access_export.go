@@ -0,0 +1,271 @@
+package main
+
+import (
+	"converter_blob/database"
+	"converter_blob/sharepoint"
+	"converter_blob/types"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scanUserFolderAccess membaca baris (email, profile_id, folder_id, file_path, folder_role).
+func scanUserFolderAccess(rows *sql.Rows) ([]types.UserFolderAccess, error) {
+	defer rows.Close()
+
+	listUserFolder := []types.UserFolderAccess{}
+
+	for rows.Next() {
+		var email string
+		var profileId string
+		var folderId string
+		var filePath string
+		var folderRole string
+		if err := rows.Scan(&email, &profileId, &folderId, &filePath, &folderRole); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+		sharepointRole, err := GetFolderRolePermission(folderRole)
+		if err != nil {
+			return nil, err
+		}
+		listUserFolder = append(listUserFolder, types.UserFolderAccess{
+			EmailAccess: types.EmailAccess{
+				Email:          email,
+				FolderRole:     folderRole,
+				SharepointRole: sharepointRole,
+			},
+			FolderId:   folderId,
+			FolderPath: filePath,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		fmt.Println("❌ Kesalahan saat membaca hasil query:", err)
+		return nil, err
+	}
+
+	return listUserFolder, nil
+}
+
+// loadAccessByScope mengambil akses folder sesuai scope: daftar profile, subtree folder,
+// satu email, atau semuanya. Hanya satu scope yang boleh diisi.
+func loadAccessByScope(db *sql.DB, scope types.AccessScope) ([]types.UserFolderAccess, error) {
+	set := 0
+	for _, on := range []bool{scope.All, scope.FolderID != "", scope.Email != "", len(scope.Profiles) > 0} {
+		if on {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("scope ganda: pilih salah satu dari --profiles, --folder-id, --email, atau --all-access")
+	}
+
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	switch {
+	case scope.All:
+		rows, err = database.GetUserAll(db)
+	case scope.FolderID != "":
+		rows, err = database.GetUserByFolderSubtree(db, scope.FolderID)
+	case scope.Email != "":
+		rows, err = database.GetUserByEmail(db, scope.Email)
+	case len(scope.Profiles) > 0:
+		var list []types.UserFolderAccess
+		for _, profileID := range scope.Profiles {
+			access, err := GetUserByProfileId(profileID, db)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, access...)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("scope kosong: isi --profiles, --folder-id, --email, atau --all-access")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanUserFolderAccess(rows)
+}
+
+// buildAccessExport mengelompokkan akses per folder; pasangan email+role yang sama hanya dicatat sekali.
+func buildAccessExport(scope types.AccessScope, list []types.UserFolderAccess, prefix string) types.AccessExport {
+	folders := make(map[string]*types.FolderAccessEntry)
+	seen := make(map[string]bool)
+
+	for _, ua := range list {
+		entry, ok := folders[ua.FolderId]
+		if !ok {
+			entry = &types.FolderAccessEntry{
+				FolderId:    ua.FolderId,
+				FolderPath:  prefix + ua.FolderPath,
+				EmailAccess: []types.EmailAccess{},
+			}
+			folders[ua.FolderId] = entry
+		}
+
+		key := ua.FolderId + "|" + strings.ToLower(ua.EmailAccess.Email) + "|" + ua.EmailAccess.FolderRole
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		entry.EmailAccess = append(entry.EmailAccess, ua.EmailAccess)
+	}
+
+	export := types.AccessExport{
+		SchemaVersion: types.AccessExportSchemaVersion,
+		GeneratedAt:   time.Now(),
+		Scope:         scope,
+		Folders:       make([]types.FolderAccessEntry, 0, len(folders)),
+	}
+	for _, entry := range folders {
+		sort.Slice(entry.EmailAccess, func(i, j int) bool {
+			a, b := entry.EmailAccess[i], entry.EmailAccess[j]
+			if a.Email != b.Email {
+				return a.Email < b.Email
+			}
+			return a.FolderRole < b.FolderRole
+		})
+		export.Folders = append(export.Folders, *entry)
+	}
+	sort.Slice(export.Folders, func(i, j int) bool {
+		return export.Folders[i].FolderPath < export.Folders[j].FolderPath
+	})
+
+	return export
+}
+
+// writeJSONAtomic menulis v ke file sementara di folder yang sama lalu rename,
+// jadi pembaca tidak pernah melihat file setengah jadi.
+func writeJSONAtomic(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// exportAccess membangun peta akses sesuai scope dan menulisnya ke outPath.
+func exportAccess(db *sql.DB, scope types.AccessScope, outPath, prefix string) error {
+	list, err := loadAccessByScope(db, scope)
+	if err != nil {
+		return fmt.Errorf("gagal mendapatkan akses folder: %w", err)
+	}
+
+	export := buildAccessExport(scope, list, prefix)
+	if err := writeJSONAtomic(outPath, export); err != nil {
+		return fmt.Errorf("gagal menulis %s: %w", outPath, err)
+	}
+
+	log.Printf("✅ Akses %d folder disimpan ke %s\n", len(export.Folders), outPath)
+	return nil
+}
+
+// loadAccessExport membaca file hasil exportAccess dan menolak schema yang tidak dikenal.
+func loadAccessExport(path string) (types.AccessExport, error) {
+	var export types.AccessExport
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return export, err
+	}
+	if err := json.Unmarshal(b, &export); err != nil {
+		return export, fmt.Errorf("gagal decode %s: %w", path, err)
+	}
+	if export.SchemaVersion != types.AccessExportSchemaVersion {
+		return export, fmt.Errorf("%s memakai schema_version %d, yang didukung %d (jalankan ulang --export-access)",
+			path, export.SchemaVersion, types.AccessExportSchemaVersion)
+	}
+
+	return export, nil
+}
+
+// parseProfiles memecah daftar profile dipisah koma.
+func parseProfiles(s string) []string {
+	var profiles []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+// resolveAccessUsers memetakan semua email di file --export-access ke user Entra ID,
+// memperbarui cache user, dan melaporkan email yang tidak ada di tenant.
+func resolveAccessUsers(path string) error {
+	export, err := loadAccessExport(path)
+	if err != nil {
+		return err
+	}
+
+	emailSet := make(map[string]bool)
+	for _, folder := range export.Folders {
+		for _, ea := range folder.EmailAccess {
+			emailSet[strings.ToLower(strings.TrimSpace(ea.Email))] = true
+		}
+	}
+	emails := make([]string, 0, len(emailSet))
+	for e := range emailSet {
+		emails = append(emails, e)
+	}
+	sort.Strings(emails)
+
+	resolver, err := sharepoint.NewDefaultResolver()
+	if err != nil {
+		return err
+	}
+	users, missing, err := resolver.ResolveAll(emails)
+	if saveErr := resolver.Save(); saveErr != nil {
+		log.Printf("⚠️ Gagal menyimpan cache user: %v\n", saveErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ %d dari %d email ditemukan di tenant\n", len(users), len(emails))
+	if len(missing) > 0 {
+		reportPath, err := writeUnresolvedReport(missing)
+		if err != nil {
+			return err
+		}
+		log.Printf("⚠️ %d email tidak ditemukan (lihat %s)\n", len(missing), reportPath)
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+// graphfake menjalankan server Graph palsu (sharepoint/graphtest) untuk uji lokal.
+//
+//	go run ./cmd/graphfake -addr 127.0.0.1:8765   # jalankan server, arahkan .env ke sini
+//	go run ./cmd/graphfake -selftest              # upload, verifikasi hash, permission dengan fault
+package main
+
+import (
+	"bytes"
+	"converter_blob/sharepoint"
+	"converter_blob/sharepoint/graphtest"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8765", "Alamat server Graph palsu")
+	selftest := flag.Bool("selftest", false, "Jalankan skenario upload dan permission terhadap server palsu lalu keluar")
+	flag.Parse()
+
+	if *selftest {
+		if err := runSelftest(); err != nil {
+			log.Fatalf("❌ Selftest gagal: %v", err)
+		}
+		log.Println("✅ Selftest berhasil")
+		return
+	}
+
+	srv := graphtest.NewUnstartedServer()
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("❌ Gagal listen %s: %v", *addr, err)
+	}
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	fmt.Println("🧪 Server Graph palsu berjalan. Pakai environment berikut:")
+	fmt.Printf("MS_GRAPH_BASE_URL=%s\n", srv.GraphURL())
+	fmt.Printf("MS_LOGIN_BASE_URL=%s\n", srv.LoginURL())
+	fmt.Println("MS_TENANT_ID=graphtest")
+	fmt.Println("MS_CLIENT_ID=graphtest")
+	fmt.Println("MS_CLIENT_SECRET=graphtest")
+	fmt.Printf("MS_DRIVE_ID=%s\n", graphtest.DriveID)
+	fmt.Printf("MS_SITE_ID=%s\n", graphtest.SiteID)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
+
+func runSelftest() error {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	srv.Use()
+
+	// 25MB → 3 chunk upload session
+	data := make([]byte, 25*1024*1024+123)
+	if _, err := rand.Read(data); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "graphfake")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	local := filepath.Join(dir, "dokumen.pdf")
+	if err := os.WriteFile(local, data, 0644); err != nil {
+		return err
+	}
+
+	srv.AddFault(graphtest.Fault{Method: http.MethodPost, Path: "createUploadSession", Status: http.StatusServiceUnavailable})
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Status: http.StatusTooManyRequests, RetryAfter: 1})
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Partial: true})
+
+	spPath := "Selftest/Folder A/dokumen.pdf"
+	item, err := sharepoint.UploadFile(local, spPath, sharepoint.UploadOptions{})
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	stored, ok := srv.Item(spPath)
+	if !ok || !bytes.Equal(stored.Content, data) {
+		return fmt.Errorf("isi file di server tidak sama dengan file lokal")
+	}
+	if item.Size != int64(len(data)) {
+		return fmt.Errorf("ukuran item %d, seharusnya %d", item.Size, len(data))
+	}
+	if item.QuickXorHash() == "" || item.QuickXorHash() != item.LocalQuickXorHash {
+		return fmt.Errorf("quickXorHash lokal %q, SharePoint %q", item.LocalQuickXorHash, item.QuickXorHash())
+	}
+	log.Println("✅ Upload chunk dengan 503/429/partial range:", spPath)
+
+	if err := selftestSmall(srv, dir); err != nil {
+		return err
+	}
+	log.Println("✅ File kecil lewat PUT /content tanpa upload session")
+
+	if err := selftestConflict(local, spPath); err != nil {
+		return err
+	}
+	log.Println("✅ Conflict policy skip, newer, fail, rename")
+
+	// chunk kedua gagal 500 → upload berikutnya melanjutkan session dari state file
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Partial: true})
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Status: http.StatusInternalServerError})
+	if _, err := sharepoint.UploadFile(local, "Selftest/resume.pdf", sharepoint.UploadOptions{}); err == nil {
+		return fmt.Errorf("upload dengan 500 seharusnya gagal")
+	}
+	item, err = sharepoint.UploadFile(local, "Selftest/resume.pdf", sharepoint.UploadOptions{})
+	if err != nil {
+		return fmt.Errorf("resume upload: %w", err)
+	}
+	log.Println("✅ Resume upload session dengan verifikasi hash:", item.LocalQuickXorHash)
+
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Corrupt: true})
+	item, err = sharepoint.UploadFile(local, "Selftest/rusak.pdf", sharepoint.UploadOptions{})
+	if !sharepoint.IsHashMismatch(err) || item == nil || item.QuickXorHash() == item.LocalQuickXorHash {
+		return fmt.Errorf("upload rusak seharusnya gagal verifikasi hash, dapat: %v", err)
+	}
+	log.Println("✅ Hash tidak cocok terdeteksi:", err)
+
+	folder, err := sharepoint.GetDriveItem("Selftest/Folder A")
+	if err != nil {
+		return fmt.Errorf("lookup folder: %w", err)
+	}
+	if err := sharepoint.InviteToItem(folder.ID, []string{"user@example.com"}, "read"); err != nil {
+		return fmt.Errorf("invite: %w", err)
+	}
+	perms, err := sharepoint.ListPermissions(folder.ID)
+	if err != nil || len(perms) != 1 || perms[0].Email() != "user@example.com" {
+		return fmt.Errorf("list permission tidak sesuai: %v %v", perms, err)
+	}
+	if err := sharepoint.UpdatePermissionRoles(folder.ID, perms[0].ID, []string{"write"}); err != nil {
+		return fmt.Errorf("update role: %w", err)
+	}
+	if err := sharepoint.DeletePermission(folder.ID, perms[0].ID); err != nil {
+		return fmt.Errorf("hapus permission: %w", err)
+	}
+	log.Println("✅ Invite, ubah role, dan hapus permission")
+
+	if err := selftestBatch(srv); err != nil {
+		return err
+	}
+	log.Println("✅ /$batch: buat folder bertingkat, retry sub-request 429, update kolom")
+
+	if _, err := sharepoint.GetDriveItem("Selftest/tidak-ada.pdf"); !sharepoint.IsNotFound(err) {
+		return fmt.Errorf("item yang tidak ada seharusnya not found, dapat: %v", err)
+	}
+	log.Println("✅ GraphError not found")
+
+	return nil
+}
+
+// selftestConflict meng-upload ulang file yang sudah ada dengan setiap ConflictPolicy.
+func selftestConflict(local, spPath string) error {
+	item, err := sharepoint.UploadFile(local, spPath, sharepoint.UploadOptions{Conflict: sharepoint.ConflictSkip})
+	if err != nil || item.Skipped == "" {
+		return fmt.Errorf("skip: file sama seharusnya dilewati (%v)", err)
+	}
+
+	old := time.Now().Add(-24 * time.Hour)
+	item, err = sharepoint.UploadFile(local, spPath, sharepoint.UploadOptions{Conflict: sharepoint.ConflictNewer, ModifiedAt: old})
+	if err != nil || item.Skipped == "" {
+		return fmt.Errorf("newer: file lebih lama seharusnya dilewati (%v)", err)
+	}
+	item, err = sharepoint.UploadFile(local, spPath, sharepoint.UploadOptions{Conflict: sharepoint.ConflictNewer, ModifiedAt: time.Now().Add(time.Hour)})
+	if err != nil || item.Skipped != "" {
+		return fmt.Errorf("newer: file lebih baru seharusnya di-upload (%v)", err)
+	}
+
+	if _, err := sharepoint.UploadFile(local, spPath, sharepoint.UploadOptions{Conflict: sharepoint.ConflictFail}); !sharepoint.IsConflict(err) {
+		return fmt.Errorf("fail: seharusnya 409, dapat %v", err)
+	}
+
+	item, err = sharepoint.UploadFile(local, spPath, sharepoint.UploadOptions{Conflict: sharepoint.ConflictRename})
+	if err != nil || item.Name == path.Base(spPath) {
+		return fmt.Errorf("rename: seharusnya nama baru, dapat %v (%v)", item, err)
+	}
+	return nil
+}
+
+// selftestBatch membuat folder bertingkat lewat EnsureFolders (sekali dengan sub-request yang
+// di-throttle, sekali lagi untuk memastikan idempotent) lalu mengisi kolom lewat UpdateItemFieldsBatch.
+func selftestBatch(srv *graphtest.Server) error {
+	paths := []string{"Selftest/Batch/A/1", "Selftest/Batch/A/2", "Selftest/Batch/B"}
+
+	srv.AddFault(graphtest.Fault{Method: http.MethodPost, Path: "/children", Status: http.StatusTooManyRequests, RetryAfter: 1})
+	before := len(srv.Requests())
+	ids, failed, err := sharepoint.EnsureFolders(paths)
+	if err != nil || len(failed) > 0 {
+		return fmt.Errorf("ensure folders: %v %v", failed, err)
+	}
+	for _, p := range []string{"Selftest", "Selftest/Batch", "Selftest/Batch/A", "Selftest/Batch/A/1", "Selftest/Batch/A/2", "Selftest/Batch/B"} {
+		it, ok := srv.Item(p)
+		if !ok || !it.Folder || ids[p] != it.ID {
+			return fmt.Errorf("folder %s tidak dibuat atau id tidak cocok (%q)", p, ids[p])
+		}
+	}
+	batches := 0
+	for _, r := range srv.Requests()[before:] {
+		if r == "POST /v1.0/$batch" {
+			batches++
+		} else if !strings.HasPrefix(r, "$batch ") {
+			return fmt.Errorf("request di luar /$batch: %s", r)
+		}
+	}
+	log.Printf("📦 EnsureFolders: %d folder lewat %d panggilan /$batch\n", len(ids), batches)
+
+	again, failed, err := sharepoint.EnsureFolders(paths)
+	if err != nil || len(failed) > 0 || len(again) != len(ids) {
+		return fmt.Errorf("ensure folders kedua: %v %v", failed, err)
+	}
+
+	// nama folder disanitasi sama seperti upload file
+	ids2, failed, err := sharepoint.EnsureFolders([]string{"Selftest/Batch/Q&A #1"})
+	if it, ok := srv.Item("Selftest/Batch/Q_A _1"); err != nil || len(failed) > 0 || !ok || ids2["Selftest/Batch/Q&A #1"] != it.ID {
+		return fmt.Errorf("folder dengan karakter tidak valid: %v %v", failed, err)
+	}
+
+	updates := make(map[string]map[string]interface{})
+	for _, p := range paths {
+		updates[ids[p]] = map[string]interface{}{"TeradocuPath": p}
+	}
+	updates["item-tidak-ada"] = map[string]interface{}{"TeradocuPath": "x"}
+	fieldErrs, err := sharepoint.UpdateItemFieldsBatch(updates)
+	if err != nil {
+		return fmt.Errorf("update kolom batch: %w", err)
+	}
+	if len(fieldErrs) != 1 || !sharepoint.IsNotFound(fieldErrs["item-tidak-ada"]) {
+		return fmt.Errorf("update kolom batch: hanya item-tidak-ada yang seharusnya gagal, dapat %v", fieldErrs)
+	}
+	for _, p := range paths {
+		if it, _ := srv.Item(p); it.Fields["TeradocuPath"] != p {
+			return fmt.Errorf("kolom %s tidak terisi: %v", p, it.Fields)
+		}
+	}
+	return nil
+}
+
+// selftestSmall memastikan file di bawah 4MB tidak membuat upload session maupun .uploadstate.
+func selftestSmall(srv *graphtest.Server, dir string) error {
+	data := make([]byte, 20*1024)
+	if _, err := rand.Read(data); err != nil {
+		return err
+	}
+	local := filepath.Join(dir, "kecil.pdf")
+	if err := os.WriteFile(local, data, 0644); err != nil {
+		return err
+	}
+
+	modified := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	before := len(srv.Requests())
+	item, err := sharepoint.UploadFile(local, "Selftest/kecil.pdf", sharepoint.UploadOptions{ModifiedAt: modified})
+	if err != nil {
+		return fmt.Errorf("upload kecil: %w", err)
+	}
+	for _, r := range srv.Requests()[before:] {
+		if strings.Contains(r, "createUploadSession") || strings.Contains(r, "/upload/") {
+			return fmt.Errorf("file kecil tidak boleh memakai upload session: %s", r)
+		}
+	}
+	if _, err := os.Stat(local + ".uploadstate"); err == nil {
+		return fmt.Errorf("file kecil tidak boleh menulis .uploadstate")
+	}
+	stored, ok := srv.Item("Selftest/kecil.pdf")
+	if !ok || !bytes.Equal(stored.Content, data) || !stored.Modified.Equal(modified) {
+		return fmt.Errorf("isi atau tanggal file kecil di server tidak sesuai")
+	}
+	if item.QuickXorHash() != item.LocalQuickXorHash {
+		return fmt.Errorf("quickXorHash file kecil tidak cocok")
+	}
+	return nil
+}
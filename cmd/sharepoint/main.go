@@ -171,7 +171,7 @@ func uploadWithRetry(job FileJob, maxRetry int) error {
 		}
 
 		// file exists
-		if strings.Contains(err.Error(), "409") {
+		if sharepoint.IsConflict(err) {
 			return err
 		}
 
@@ -204,7 +204,7 @@ func worker(
 
 			stats.mu.Lock()
 
-			if strings.Contains(err.Error(), "409") {
+			if sharepoint.IsConflict(err) {
 				stats.exists++
 			} else {
 				stats.failed++
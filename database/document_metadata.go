@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// GetDocumentMetadata mengambil satu baris teradocu.document_metadata sebagai map kolom → nilai,
+// supaya mapping ke kolom SharePoint bisa dikonfigurasi tanpa mengubah query.
+func GetDocumentMetadata(db *sql.DB, documentID string, version int64) (map[string]interface{}, error) {
+	sql := `SELECT * FROM teradocu.document_metadata doc_meta WHERE doc_meta.document_id = $1 AND doc_meta.version = $2`
+
+	rows, err := db.Query(sql, documentID, version)
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("metadata %s v%d tidak ditemukan", documentID, version)
+	}
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(columns))
+	for i, col := range columns {
+		// driver mengembalikan text sebagai []byte
+		if b, ok := values[i].([]byte); ok {
+			row[col] = string(b)
+		} else {
+			row[col] = values[i]
+		}
+	}
+
+	return row, nil
+}
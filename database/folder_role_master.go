@@ -16,3 +16,26 @@ func GetFolderRoleMaster(db *sql.DB) (*sql.Rows, error) {
 
 	return rows, nil
 }
+
+// GetFolderRolesInUse mengambil folder_role yang benar-benar dipakai di folder_profile_role.
+func GetFolderRolesInUse(db *sql.DB) ([]string, error) {
+	sql := `SELECT DISTINCT fpr.folder_role FROM teradocu.folder_profile_role fpr WHERE fpr.folder_role IS NOT NULL`
+
+	rows, err := db.Query(sql)
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+const (
+	// LargeObjectChunkSize adalah ukuran satu kali loread/substring (1MB)
+	LargeObjectChunkSize = 1024 * 1024
+
+	invRead = 0x40000 // INV_READ dari libpq
+)
+
+// StreamLargeObject menyalin large object oid ke w per chunk memakai lo_open/loread
+// di dalam satu transaksi, sehingga memori tetap sebesar satu chunk berapapun ukuran dokumennya.
+func StreamLargeObject(ctx context.Context, db *sql.DB, oid uint32, w io.Writer) (int64, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, fmt.Errorf("gagal memulai transaksi: %w", err)
+	}
+	// large object descriptor hanya valid selama transaksi, rollback otomatis menutupnya
+	defer tx.Rollback()
+
+	var fd int32
+	if err := tx.QueryRowContext(ctx, "SELECT lo_open($1, $2)", oid, invRead).Scan(&fd); err != nil {
+		return 0, fmt.Errorf("gagal lo_open %d: %w", oid, err)
+	}
+
+	var total int64
+	for {
+		var chunk []byte
+		if err := tx.QueryRowContext(ctx, "SELECT loread($1, $2)", fd, LargeObjectChunkSize).Scan(&chunk); err != nil {
+			return total, fmt.Errorf("gagal loread %d: %w", oid, err)
+		}
+		if len(chunk) == 0 {
+			break
+		}
+
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "SELECT lo_close($1)", fd); err != nil {
+		return total, fmt.Errorf("gagal lo_close %d: %w", oid, err)
+	}
+
+	return total, tx.Commit()
+}
+
+// StreamDocumentPdf menyalin kolom bytea document_binary_large.pdf ke w per potongan
+// memakai substring, supaya kolom tidak pernah di-scan utuh ke memori.
+func StreamDocumentPdf(ctx context.Context, db *sql.DB, documentID string, version int64, w io.Writer) (int64, error) {
+	query := `SELECT substring(pdf FROM $3 FOR $4)
+	FROM teradocu.document_binary_large
+	WHERE document_id = $1 AND version = $2`
+
+	var total int64
+	for {
+		var chunk []byte
+		// substring bytea dimulai dari posisi 1
+		err := db.QueryRowContext(ctx, query, documentID, version, total+1, LargeObjectChunkSize).Scan(&chunk)
+		if err != nil {
+			return total, fmt.Errorf("gagal membaca pdf %s v%d: %w", documentID, version, err)
+		}
+		if len(chunk) == 0 {
+			break
+		}
+
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		if len(chunk) < LargeObjectChunkSize {
+			break
+		}
+	}
+
+	return total, nil
+}
+
+// LargeObjectSize mengembalikan ukuran large object oid dalam byte.
+func LargeObjectSize(ctx context.Context, db *sql.DB, oid uint32) (int64, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, fmt.Errorf("gagal memulai transaksi: %w", err)
+	}
+	defer tx.Rollback()
+
+	var fd int32
+	if err := tx.QueryRowContext(ctx, "SELECT lo_open($1, $2)", oid, invRead).Scan(&fd); err != nil {
+		return 0, fmt.Errorf("gagal lo_open %d: %w", oid, err)
+	}
+
+	var size int64
+	// SEEK_END = 2
+	if err := tx.QueryRowContext(ctx, "SELECT lo_lseek64($1, 0, 2)", fd).Scan(&size); err != nil {
+		return 0, fmt.Errorf("gagal lo_lseek64 %d: %w", oid, err)
+	}
+
+	return size, tx.Commit()
+}
+
+// LargeObjectReaderAt membaca large object secara acak dengan lo_get(oid, offset, length),
+// sehingga bisa dipakai langsung sebagai sumber upload tanpa file lokal.
+type LargeObjectReaderAt struct {
+	DB  *sql.DB
+	OID uint32
+}
+
+func (r *LargeObjectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	var chunk []byte
+	err := r.DB.QueryRow("SELECT lo_get($1, $2, $3)", r.OID, off, len(p)).Scan(&chunk)
+	if err != nil {
+		return 0, fmt.Errorf("gagal lo_get %d offset %d: %w", r.OID, off, err)
+	}
+	n := copy(p, chunk)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// PdfReaderAt membaca kolom bytea document_binary_large.pdf secara acak dengan substring.
+type PdfReaderAt struct {
+	DB         *sql.DB
+	DocumentID string
+	Version    int64
+}
+
+func (r *PdfReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	query := `SELECT substring(pdf FROM $3 FOR $4)
+	FROM teradocu.document_binary_large
+	WHERE document_id = $1 AND version = $2`
+
+	var chunk []byte
+	err := r.DB.QueryRow(query, r.DocumentID, r.Version, off+1, len(p)).Scan(&chunk)
+	if err != nil {
+		return 0, fmt.Errorf("gagal membaca pdf %s v%d offset %d: %w", r.DocumentID, r.Version, off, err)
+	}
+	n := copy(p, chunk)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
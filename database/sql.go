@@ -3,6 +3,8 @@ package database
 import (
 	"database/sql"
 	"fmt"
+
+	"github.com/lib/pq"
 )
 
 func GetUserByFolderId(folderId string, db *sql.DB) (*sql.Rows, error) {
@@ -35,14 +37,15 @@ func GetUserByFolderId(folderId string, db *sql.DB) (*sql.Rows, error) {
 		pr.email,
 		fpr.profile_id,
 		ih2.id AS folder_id,
-		ih2.file_path
+		ih2.file_path,
+		fpr.folder_role
 	FROM item_hierarchy ih2
 	JOIN teradocu.folder_profile_role fpr ON fpr.folder_id = ih2.id
 	JOIN teradocu.user_profile up on fpr.profile_id = up.profile_id
 	JOIN teradocu.employee_user eu1 ON up.user_id = eu1.id
 	JOIN teradocu.person pr ON pr.id = eu1.person_id
 	WHERE ih2.id = $1 AND eu1.active = true
-	GROUP BY ih2.id, pr.email, fpr.profile_id, ih2.file_path`
+	GROUP BY ih2.id, pr.email, fpr.profile_id, ih2.file_path, fpr.folder_role`
 
 	rows, err := db.Query(sql, folderId)
 	if err != nil {
@@ -133,14 +136,15 @@ func GetUserByEmail(db *sql.DB, email string) (*sql.Rows, error) {
 		pr.email,
 		fpr.profile_id,
 		ih2.id AS folder_id,
-		ih2.file_path
+		ih2.file_path,
+		fpr.folder_role
 	FROM item_hierarchy ih2
 	JOIN teradocu.folder_profile_role fpr ON fpr.folder_id = ih2.id
 	JOIN teradocu.user_profile up on fpr.profile_id = up.profile_id
 	JOIN teradocu.employee_user eu1 ON up.user_id = eu1.id
 	JOIN teradocu.person pr ON pr.id = eu1.person_id
-	WHERE pr.email = '$1' AND eu1.active = true
-	GROUP BY ih2.id, pr.email, fpr.profile_id, ih2.file_path`
+	WHERE lower(pr.email) = lower($1) AND eu1.active = true
+	GROUP BY ih2.id, pr.email, fpr.profile_id, ih2.file_path, fpr.folder_role`
 
 	rows, err := db.Query(sql, email)
 	if err != nil {
@@ -181,14 +185,15 @@ func GetUserByID(db *sql.DB, userID string) (*sql.Rows, error) {
 		pr.email,
 		fpr.profile_id,
 		ih2.id AS folder_id,
-		ih2.file_path
+		ih2.file_path,
+		fpr.folder_role
 	FROM item_hierarchy ih2
 	JOIN teradocu.folder_profile_role fpr ON fpr.folder_id = ih2.id
 	JOIN teradocu.user_profile up on fpr.profile_id = up.profile_id
 	JOIN teradocu.employee_user eu1 ON up.user_id = eu1.id
 	JOIN teradocu.person pr ON pr.id = eu1.person_id
-	WHERE eu1.id = '$1' AND eu1.active = true
-	GROUP BY ih2.id, pr.email, fpr.profile_id, ih2.file_path`
+	WHERE eu1.id = $1 AND eu1.active = true
+	GROUP BY ih2.id, pr.email, fpr.profile_id, ih2.file_path, fpr.folder_role`
 
 	rows, err := db.Query(sql, userID)
 	if err != nil {
@@ -198,3 +203,191 @@ func GetUserByID(db *sql.DB, userID string) (*sql.Rows, error) {
 
 	return rows, nil
 }
+
+// GetUserByFolderSubtree mengambil akses user aktif untuk folder folderID dan semua subfoldernya.
+func GetUserByFolderSubtree(db *sql.DB, folderID string) (*sql.Rows, error) {
+	sql := `WITH RECURSIVE subtree AS (
+		SELECT id, fullpath AS file_path
+		FROM teradocu.folder
+		WHERE id = $1
+		UNION ALL
+		SELECT f.id, f.fullpath AS file_path
+		FROM teradocu.folder f
+		JOIN subtree st ON f.parent_id = st.id
+	)
+	SELECT
+		pr.email,
+		fpr.profile_id,
+		st.id AS folder_id,
+		st.file_path,
+		fpr.folder_role
+	FROM subtree st
+	JOIN teradocu.folder_profile_role fpr ON fpr.folder_id = st.id
+	JOIN teradocu.user_profile up on fpr.profile_id = up.profile_id
+	JOIN teradocu.employee_user eu1 ON up.user_id = eu1.id
+	JOIN teradocu.person pr ON pr.id = eu1.person_id
+	WHERE eu1.active = true
+	GROUP BY st.id, pr.email, fpr.profile_id, st.file_path, fpr.folder_role`
+
+	rows, err := db.Query(sql, folderID)
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetUserAll mengambil akses semua user aktif di semua folder yang tidak dihapus.
+func GetUserAll(db *sql.DB) (*sql.Rows, error) {
+	sql := `SELECT
+		pr.email,
+		fpr.profile_id,
+		f.id AS folder_id,
+		f.fullpath AS file_path,
+		fpr.folder_role
+	FROM teradocu.folder f
+	JOIN teradocu.folder_profile_role fpr ON fpr.folder_id = f.id
+	JOIN teradocu.user_profile up on fpr.profile_id = up.profile_id
+	JOIN teradocu.employee_user eu1 ON up.user_id = eu1.id
+	JOIN teradocu.person pr ON pr.id = eu1.person_id
+	WHERE f.is_deleted is false AND eu1.active = true
+	GROUP BY f.id, pr.email, fpr.profile_id, f.fullpath, fpr.folder_role`
+
+	rows, err := db.Query(sql)
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetFolderAccessAll mengambil semua hak akses folder (folder_profile_role) untuk user aktif,
+// dibatasi ke folder yang fullpath-nya mengandung pathFilter.
+func GetFolderAccessAll(db *sql.DB, pathFilter string) (*sql.Rows, error) {
+	sql := `SELECT
+		pr.email,
+		fpr.profile_id,
+		f.id AS folder_id,
+		f.parent_id,
+		f.fullpath AS file_path,
+		fpr.folder_role
+	FROM teradocu.folder f
+	JOIN teradocu.folder_profile_role fpr ON fpr.folder_id = f.id
+	JOIN teradocu.user_profile up on fpr.profile_id = up.profile_id
+	JOIN teradocu.employee_user eu1 ON up.user_id = eu1.id
+	JOIN teradocu.person pr ON pr.id = eu1.person_id
+	WHERE f.is_deleted is false AND eu1.active = true AND f.fullpath ILIKE '%' || $1 || '%'
+	GROUP BY f.id, f.parent_id, pr.email, fpr.profile_id, f.fullpath, fpr.folder_role`
+
+	rows, err := db.Query(sql, pathFilter)
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetFolderList mengambil folder yang tidak dihapus dan fullpath-nya mengandung pathFilter.
+func GetFolderList(db *sql.DB, pathFilter string) (*sql.Rows, error) {
+	sql := `SELECT fl.id, fl.parent_id, fl.fullpath
+	FROM teradocu.folder fl
+	WHERE fl.is_deleted is false AND fl.fullpath ILIKE '%' || $1 || '%'
+	ORDER BY fl.fullpath`
+
+	rows, err := db.Query(sql, pathFilter)
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetEmployeeStatusByEmails mengambil status user Teradocu per email (lowercase):
+// active bernilai true kalau ada employee_user aktif, mapped kalau email punya employee_user sama sekali.
+// Email yang tidak ada di teradocu.person tidak ikut dikembalikan.
+func GetEmployeeStatusByEmails(db *sql.DB, emails []string) (*sql.Rows, error) {
+	sql := `SELECT
+		lower(pr.email) AS email,
+		COALESCE(bool_or(eu1.active), false) AS active,
+		count(eu1.id) > 0 AS mapped
+	FROM teradocu.person pr
+	LEFT JOIN teradocu.employee_user eu1 ON eu1.person_id = pr.id
+	WHERE lower(pr.email) = ANY($1)
+	GROUP BY lower(pr.email)`
+
+	rows, err := db.Query(sql, pq.Array(emails))
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetInactiveEmployees mengambil email (lowercase) user Teradocu yang tidak punya employee_user
+// aktif; mapped bernilai false kalau email tidak terhubung ke employee_user sama sekali.
+func GetInactiveEmployees(db *sql.DB) (*sql.Rows, error) {
+	sql := `SELECT
+		lower(pr.email) AS email,
+		count(eu1.id) > 0 AS mapped
+	FROM teradocu.person pr
+	LEFT JOIN teradocu.employee_user eu1 ON eu1.person_id = pr.id
+	WHERE COALESCE(pr.email, '') <> ''
+	GROUP BY lower(pr.email)
+	HAVING NOT COALESCE(bool_or(eu1.active), false)`
+
+	rows, err := db.Query(sql)
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetProfileFolderAccess mengambil hak akses folder per profile (tanpa dipecah ke email),
+// dibatasi ke folder yang fullpath-nya mengandung pathFilter.
+func GetProfileFolderAccess(db *sql.DB, pathFilter string) (*sql.Rows, error) {
+	sql := `SELECT
+		fpr.profile_id,
+		f.id AS folder_id,
+		f.parent_id,
+		f.fullpath AS file_path,
+		fpr.folder_role
+	FROM teradocu.folder f
+	JOIN teradocu.folder_profile_role fpr ON fpr.folder_id = f.id
+	WHERE f.is_deleted is false AND f.fullpath ILIKE '%' || $1 || '%'
+	GROUP BY fpr.profile_id, f.id, f.parent_id, f.fullpath, fpr.folder_role`
+
+	rows, err := db.Query(sql, pathFilter)
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetProfileMembers mengambil email user aktif (lowercase) anggota tiap profile di profileIDs.
+func GetProfileMembers(db *sql.DB, profileIDs []string) (*sql.Rows, error) {
+	sql := `SELECT
+		up.profile_id,
+		lower(pr.email) AS email
+	FROM teradocu.user_profile up
+	JOIN teradocu.employee_user eu1 ON up.user_id = eu1.id
+	JOIN teradocu.person pr ON pr.id = eu1.person_id
+	WHERE up.profile_id = ANY($1) AND eu1.active = true AND pr.email IS NOT NULL
+	GROUP BY up.profile_id, lower(pr.email)`
+
+	rows, err := db.Query(sql, pq.Array(profileIDs))
+	if err != nil {
+		fmt.Println("❌ Gagal menjalankan query:", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
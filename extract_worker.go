@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"converter_blob/database"
+	"converter_blob/manifest"
+	"converter_blob/sharepoint"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultExtractWorkers = 4
+	directUploadRetry     = 3
+	directStateDir        = "data/uploadstate"
+)
+
+// extractJob adalah satu baris metadata dokumen hasil query ekstraksi.
+type extractJob struct {
+	seq                                    int
+	documentID                             string
+	version                                int64
+	fileName, mimeType, fileType, fullPath string
+	pdfLen                                 int64
+	binaryOid                              sql.NullInt64
+	folderId                               string
+	metaSize                               sql.NullInt64
+	modifiedAt                             time.Time
+	createdAt                              time.Time
+	// mappedPath adalah path SharePoint hasil pathmap, juga dipakai untuk file lokal
+	mappedPath string
+}
+
+type extractStatus int
+
+const (
+	statusExtracted extractStatus = iota
+	statusResumed                 // sudah diekstrak run sebelumnya, tinggal upload
+	statusDone                    // sudah selesai (uploaded/skipped) di run sebelumnya
+	statusSkipped                 // --no-replace dan file sudah ada, atau dilewati --sp-conflict
+	statusFailed
+	statusIgnored  // tidak ada metadata/konten, tidak dicatat
+	statusUploaded // --direct-sp: langsung di-upload tanpa file lokal
+)
+
+type extractResult struct {
+	job       extractJob
+	worker    int
+	status    extractStatus
+	file      extracted
+	sizeBytes int64
+	checksums fileChecksums
+	err       error
+
+	item *sharepoint.ItemResponse // --direct-sp: drive item hasil upload (untuk hash di manifest)
+}
+
+type workerStats struct {
+	files int64
+	bytes int64
+}
+
+// extractor menjalankan ekstraksi satu dokumen; dipakai bersama oleh semua worker.
+type extractor struct {
+	db         *sql.DB
+	store      *manifest.Store
+	noReplace  bool
+	onlyUpload bool
+	direct     bool
+	versioned  bool
+	quickXor   bool // --quickxor: QuickXorHash ikut dihitung saat menulis file
+	conflict   sharepoint.ConflictPolicy
+	timestamp  string
+	budget     *diskBudget
+	fields     *fieldUpdater
+	stats      []workerStats
+}
+
+// extractWorkerCount memilih jumlah worker: flag --workers, lalu EXTRACT_WORKERS, lalu default.
+func extractWorkerCount(flagValue int) int {
+	if flagValue > 0 {
+		return flagValue
+	}
+	if v := os.Getenv("EXTRACT_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("⚠️ EXTRACT_WORKERS tidak valid: %q, pakai default %d\n", v, defaultExtractWorkers)
+	}
+	return defaultExtractWorkers
+}
+
+// tuneDBPool menyesuaikan pool koneksi: satu koneksi per worker ditambah cursor query utama.
+func tuneDBPool(db *sql.DB, workers int) {
+	db.SetMaxOpenConns(workers + 2)
+	db.SetMaxIdleConns(workers + 2)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+}
+
+// produceExtractJobs membaca rows dan mengirimkannya ke jobs dengan nomor urut.
+func produceExtractJobs(rows *sql.Rows, jobs chan<- extractJob) error {
+	defer close(jobs)
+
+	seq := 0
+	for rows.Next() {
+		var j extractJob
+		var modified, created sql.NullTime
+		if err := rows.Scan(&j.documentID, &j.version, &j.fileName, &j.mimeType, &j.fileType, &j.fullPath, &j.pdfLen, &j.binaryOid, &j.folderId, &j.metaSize, &modified, &created); err != nil {
+			log.Printf("❌ Failed to scan row: %v\n", err)
+			continue
+		}
+		if modified.Valid {
+			j.modifiedAt = modified.Time
+		}
+		if created.Valid {
+			j.createdAt = created.Time
+		}
+		// dipetakan di sini (satu goroutine, urutan query) supaya penyelesaian nama bentrok deterministik
+		j.mappedPath = spPaths.MapFile(j.fullPath, j.fileName)
+		j.seq = seq
+		seq++
+		jobs <- j
+	}
+
+	return rows.Err()
+}
+
+// run menjalankan n worker yang membaca jobs dan mengirim hasil ke channel yang dikembalikan.
+func (x *extractor) run(jobs <-chan extractJob, n int) <-chan extractResult {
+	results := make(chan extractResult, n*2)
+	x.stats = make([]workerStats, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for job := range jobs {
+				results <- x.extract(id, job)
+			}
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+func (x *extractor) extract(workerID int, job extractJob) extractResult {
+	res := extractResult{job: job, worker: workerID}
+
+	outputPath := filepath.Join(exportFolder, filepath.FromSlash(job.mappedPath))
+	spPath := fmt.Sprintf("%s/%s", x.timestamp, job.mappedPath)
+	// semua versi menuju item SharePoint yang sama, tapi di disk perlu nama berbeda per versi
+	if x.versioned {
+		outputPath = versionedPath(outputPath, job.version)
+	}
+
+	res.file = extracted{
+		localPath:      outputPath,
+		sharePointPath: spPath,
+		documentID:     job.documentID,
+		version:        job.version,
+		modifiedAt:     job.modifiedAt,
+		createdAt:      job.createdAt,
+	}
+
+	// ================= RESUME =================
+
+	if prev, ok := x.store.Get(job.documentID, job.version); ok {
+		if prev.IsUploaded() || prev.State == manifest.StateSkipped {
+			res.status = statusDone
+			return res
+		}
+		if prev.IsExtracted() && !x.onlyUpload {
+			if fi, err := os.Stat(prev.LocalPath); err == nil && fi.Size() == prev.SizeBytes {
+				res.status = statusResumed
+				res.file.localPath = prev.LocalPath
+				res.file.sharePointPath = prev.SPPath
+				res.file.sizeMB = float64(prev.SizeBytes) / (1024 * 1024)
+				return res
+			}
+			log.Printf("⚠️ File hasil run sebelumnya hilang/berubah, ekstrak ulang: %s\n", prev.LocalPath)
+		}
+	}
+
+	if x.direct {
+		return x.uploadDirect(workerID, job, res)
+	}
+
+	if x.onlyUpload {
+		if !job.metaSize.Valid {
+			log.Println("⚠️ Skipping: no size metadata")
+			res.status = statusIgnored
+			return res
+		}
+		res.status = statusExtracted
+		res.file.sizeMB = float64(job.metaSize.Int64) / (1024 * 1024)
+		res.file.isDummy = true
+		return res
+	}
+
+	var copyFn func(w io.Writer) (int64, error)
+	if job.mimeType == "application/pdf" && job.pdfLen > 0 {
+		copyFn = func(w io.Writer) (int64, error) {
+			return database.StreamDocumentPdf(context.Background(), x.db, job.documentID, job.version, w)
+		}
+	} else if job.binaryOid.Valid {
+		oid := uint32(job.binaryOid.Int64)
+		copyFn = func(w io.Writer) (int64, error) {
+			return database.StreamLargeObject(context.Background(), x.db, oid, w)
+		}
+	} else {
+		res.status = statusFailed
+		res.err = fmt.Errorf("no valid content")
+		return res
+	}
+
+	_ = os.MkdirAll(filepath.Dir(outputPath), os.ModePerm)
+
+	if x.noReplace {
+		if _, err := os.Stat(outputPath); err == nil {
+			res.status = statusSkipped
+			return res
+		}
+	}
+
+	// estimasi ukuran dari metadata; worker menunggu di sini kalau disk budget penuh
+	reserve := job.pdfLen
+	if job.metaSize.Valid && job.metaSize.Int64 > reserve {
+		reserve = job.metaSize.Int64
+	}
+	if err := x.budget.acquire(reserve); err != nil {
+		res.status = statusFailed
+		res.err = err
+		return res
+	}
+
+	size, checksums, err := streamToFile(outputPath, x.quickXor, copyFn)
+	if err != nil {
+		x.budget.release(reserve)
+		res.status = statusFailed
+		res.err = err
+		return res
+	}
+	res.file.reserved = reserve
+
+	atomic.AddInt64(&x.stats[workerID].files, 1)
+	atomic.AddInt64(&x.stats[workerID].bytes, size)
+
+	res.status = statusExtracted
+	res.sizeBytes = size
+	res.checksums = checksums
+	res.file.sizeMB = float64(size) / (1024 * 1024)
+	return res
+}
+
+// uploadDirect mengalirkan konten dokumen dari PostgreSQL langsung ke upload session SharePoint
+// tanpa menulis file lokal. State session disimpan di data/uploadstate supaya bisa dilanjutkan.
+func (x *extractor) uploadDirect(workerID int, job extractJob, res extractResult) extractResult {
+	var (
+		src  io.ReaderAt
+		size int64
+		err  error
+	)
+
+	if job.mimeType == "application/pdf" && job.pdfLen > 0 {
+		src = &database.PdfReaderAt{DB: x.db, DocumentID: job.documentID, Version: job.version}
+		size = job.pdfLen
+	} else if job.binaryOid.Valid {
+		oid := uint32(job.binaryOid.Int64)
+		src = &database.LargeObjectReaderAt{DB: x.db, OID: oid}
+		size, err = database.LargeObjectSize(context.Background(), x.db, oid)
+		if err != nil {
+			res.status = statusFailed
+			res.err = err
+			return res
+		}
+	} else {
+		res.status = statusFailed
+		res.err = fmt.Errorf("no valid content")
+		return res
+	}
+
+	_ = os.MkdirAll(directStateDir, os.ModePerm)
+	opts := sharepoint.UploadOptions{
+		StateFile:  filepath.Join(directStateDir, fmt.Sprintf("%s_v%d.uploadstate", job.documentID, job.version)),
+		StateKey:   res.file.sharePointPath,
+		ModifiedAt: job.modifiedAt,
+		CreatedAt:  job.createdAt,
+		Conflict:   x.conflict,
+	}
+
+	var item *sharepoint.ItemResponse
+	for attempt := 1; attempt <= directUploadRetry; attempt++ {
+		item, err = sharepoint.UploadReaderAt(src, size, res.file.sharePointPath, opts)
+		if err == nil || sharepoint.IsConflict(err) || sharepoint.IsHashMismatch(err) {
+			break
+		}
+		log.Printf("🔄 Retry %d: %s (%v)\n", attempt, res.file.sharePointPath, err)
+		time.Sleep(time.Duration(attempt) * 2 * time.Second)
+	}
+	res.item = item
+	if err != nil {
+		res.status = statusFailed
+		res.err = err
+		return res
+	}
+	if item.Skipped != "" {
+		res.status = statusSkipped
+		return res
+	}
+
+	atomic.AddInt64(&x.stats[workerID].files, 1)
+	atomic.AddInt64(&x.stats[workerID].bytes, size)
+
+	res.status = statusUploaded
+	res.sizeBytes = size
+	res.file.localPath = ""
+	res.file.sizeMB = float64(size) / (1024 * 1024)
+	return res
+}
+
+// versionedPath menambahkan nomor versi sebelum ekstensi: laporan.pdf → laporan__v3.pdf
+func versionedPath(path string, version int64) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s__v%d%s", strings.TrimSuffix(path, ext), version, ext)
+}
+
+// reportProgress mencetak progress per worker secara berkala sampai done ditutup.
+func (x *extractor) reportProgress(done <-chan struct{}, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			x.logWorkerStats()
+		}
+	}
+}
+
+func (x *extractor) logWorkerStats() {
+	for i := range x.stats {
+		files := atomic.LoadInt64(&x.stats[i].files)
+		bytes := atomic.LoadInt64(&x.stats[i].bytes)
+		log.Printf("👷 Worker %d: %d file, %.2f MB\n", i+1, files, float64(bytes)/(1024*1024))
+	}
+}
@@ -2,25 +2,27 @@ package main
 
 import (
 	"bufio"
-	"context"
 	"converter_blob/database"
-	"converter_blob/logs"
+	"converter_blob/manifest"
+	"converter_blob/pathmap"
 	"converter_blob/sharepoint"
 	"converter_blob/types"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/csv"
-	"encoding/json"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -35,6 +37,9 @@ const (
 	// BuildDate      = "2024-06-09T00:00:00Z" // Update this with your build date
 )
 
+// spPaths memetakan path Teradocu ke path SharePoint (dan file lokal); tabelnya di data/path_map.csv.
+var spPaths *pathmap.Mapper
+
 func printVersion() {
 	fmt.Printf("📦 Versi: %s\n🕒 Dibangun: %s\n", Version, BuildDate)
 }
@@ -50,6 +55,32 @@ func main() {
 	endFlag := flag.Int("end", 0, "end pada offset tertentu (default 0)")
 	withUploadSharepointFlag := flag.Bool("with-upload-sp", false, "Sertakan upload ke SharePoint")
 	noReplace := flag.Bool("no-replace", false, "Jangan timpa file yang sudah ada")
+	resumeFlag := flag.String("resume", "", "Lanjutkan run ekstraksi/upload sebelumnya berdasarkan run-id")
+	workersFlag := flag.Int("workers", 0, "Jumlah worker ekstraksi paralel (default EXTRACT_WORKERS atau 4)")
+	pipelineFlag := flag.Bool("pipeline", false, "Upload tiap file ke SharePoint langsung setelah diekstrak (dengan --with-upload-sp)")
+	deleteAfterUploadFlag := flag.Bool("delete-after-upload", false, "Hapus file lokal setelah upload terverifikasi")
+	directSharepointFlag := flag.Bool("direct-sp", false, "Upload langsung dari PostgreSQL ke SharePoint tanpa file lokal (dengan --extract)")
+	allVersionsFlag := flag.Bool("all-versions", false, "Ekstrak dan upload semua versi dokumen, bukan hanya versi terakhir")
+	fieldMappingFlag := flag.String("sp-fields", "", "File JSON mapping kolom document_metadata ke kolom SharePoint (default SP_FIELD_MAPPING)")
+	maxDiskFlag := flag.Int64("max-disk-mb", 0, "Batas MB file lokal hasil ekstraksi di disk pada mode --pipeline, wajib dengan --delete-after-upload (0 = tanpa batas)")
+	spConflictFlag := flag.String("sp-conflict", "replace", "Kalau file sudah ada di SharePoint: replace, skip (ukuran+hash sama), rename, fail, atau newer (tanggal modified)")
+	quickXorFlag := flag.Bool("quickxor", false, "Hitung juga QuickXorHash (hash SharePoint) saat ekstraksi")
+	verifyLocalFlag := flag.Bool("verify-local", false, "Hitung ulang hash file di pdf_exports dan laporkan yang terpotong/berubah sejak ekstraksi")
+
+	createSPFoldersFlag := flag.Bool("create-sp-folders", false, "Buat seluruh struktur folder Teradocu di SharePoint dan simpan drive item id ke data/sp_folders.json")
+	syncPermissionsFlag := flag.Bool("sync-permissions", false, "Terapkan hak akses folder Teradocu ke SharePoint")
+	auditPermissionsFlag := flag.Bool("audit-permissions", false, "Bandingkan akses folder Teradocu dengan permission SharePoint")
+	exportAccessFlag := flag.Bool("export-access", false, "Simpan peta akses folder Teradocu ke JSON (lihat --profiles, --folder-id, --email, --all-access)")
+	profilesFlag := flag.String("profiles", "", "Daftar profile_id dipisah koma untuk --export-access")
+	folderIDFlag := flag.String("folder-id", "", "Folder Teradocu (beserta subfolder) untuk --export-access")
+	emailFlag := flag.String("email", "", "Email user untuk --export-access")
+	allAccessFlag := flag.Bool("all-access", false, "Ekspor akses semua folder untuk --export-access")
+	accessOutFlag := flag.String("access-out", fileUserAccess, "File output --export-access")
+	resolveUsersFlag := flag.Bool("resolve-users", false, "Cocokkan email di file --access-out dengan user Entra ID dan laporkan yang tidak ada")
+	syncGroupsFlag := flag.Bool("sync-groups", false, "Kelola akses folder per profile Teradocu lewat group, bukan per user")
+	groupTypeFlag := flag.String("group-type", "entra", "Jenis group untuk --sync-groups: entra (security group) atau site (SharePoint site group)")
+	revokeInactiveFlag := flag.Bool("revoke-inactive", false, "Cabut akses SharePoint milik user Teradocu yang sudah tidak aktif")
+	dryRunFlag := flag.Bool("dry-run", false, "Tampilkan perubahan permission tanpa menerapkannya")
 
 	onlyUploadSharepoint := flag.Bool("only-upload-sp", false, "Hanya upload ke SharePoint tanpa ekstraksi")
 
@@ -81,7 +112,31 @@ func main() {
 	if *folderPath != "" {
 		modeFlags++
 	}
-	if *extractFlag {
+	if *extractFlag || *resumeFlag != "" {
+		modeFlags++
+	}
+	if *createSPFoldersFlag {
+		modeFlags++
+	}
+	if *syncPermissionsFlag {
+		modeFlags++
+	}
+	if *auditPermissionsFlag {
+		modeFlags++
+	}
+	if *syncGroupsFlag {
+		modeFlags++
+	}
+	if *exportAccessFlag {
+		modeFlags++
+	}
+	if *resolveUsersFlag {
+		modeFlags++
+	}
+	if *revokeInactiveFlag {
+		modeFlags++
+	}
+	if *verifyLocalFlag {
 		modeFlags++
 	}
 	if *versionFlag {
@@ -95,12 +150,31 @@ func main() {
 		fmt.Println("   --file <file>    Upload satu file PDF")
 		fmt.Println("   --folder <dir>   Upload semua PDF dari folder")
 		fmt.Println("   --extract        Ekstrak semua PDF dari DB")
+		fmt.Println("   --create-sp-folders Buat struktur folder Teradocu di SharePoint (id di data/sp_folders.json)")
+		fmt.Println("   --sync-permissions  Terapkan akses folder Teradocu ke SharePoint (opsional --dry-run)")
+		fmt.Println("   --audit-permissions Laporan selisih akses Teradocu vs SharePoint")
+		fmt.Println("   --export-access     Simpan akses folder ke JSON (--profiles, --folder-id, --email atau --all-access)")
+		fmt.Println("   --resolve-users     Cek email hasil --export-access ke Entra ID (cache di data/user_cache.json)")
+		fmt.Println("   --sync-groups       Share folder ke group per profile Teradocu (--group-type entra|site, opsional --dry-run)")
+		fmt.Println("   --revoke-inactive   Cabut akses user Teradocu nonaktif (opsional --dry-run)")
+		fmt.Println("   --verify-local      Cek file di pdf_exports terhadap checksum di manifest")
 		fmt.Println("   --version        Tampilkan versi aplikasi")
 		fmt.Println("   --no-replace     Jangan timpa file yang sudah ada")
+		fmt.Println("   --resume <id>    Lanjutkan run ekstraksi/upload sebelumnya")
+		fmt.Println("   --pipeline       Upload langsung setelah ekstrak (dengan --with-upload-sp)")
+		fmt.Println("   --direct-sp      Upload dari DB ke SharePoint tanpa file lokal (dengan --extract)")
+		fmt.Println("   --all-versions   Migrasi seluruh riwayat versi dokumen")
+		fmt.Println("   --quickxor       Simpan juga QuickXorHash saat ekstraksi")
+		fmt.Println("   --sp-conflict <p> replace|skip|rename|fail|newer untuk file yang sudah ada di SharePoint")
 		fmt.Println("   (opsional) --env <env>  Pilih environment .env.dev / .env.prod")
 		os.Exit(1)
 	}
 
+	if err := checkDiskBudgetFlags(*pipelineFlag, *deleteAfterUploadFlag, *maxDiskFlag); err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
+
 	if *env != "" {
 		loadEnv(*env)
 	}
@@ -117,6 +191,26 @@ func main() {
 	}
 	defer db.Close()
 
+	spPaths, err = pathmap.OpenDefault()
+	if err != nil {
+		log.Fatalf("❌ Gagal membaca tabel pemetaan path: %v", err)
+	}
+	defer spPaths.Close()
+	// mode --resume baru tahu apakah ada upload setelah manifest dibaca
+	usesSharepoint := *singleFile != "" || *folderPath != "" || *createSPFoldersFlag ||
+		*syncPermissionsFlag || *auditPermissionsFlag || *syncGroupsFlag || *revokeInactiveFlag ||
+		(*resumeFlag == "" && (*withUploadSharepointFlag || *onlyUploadSharepoint || *directSharepointFlag))
+	if usesSharepoint {
+		initLibraryURL()
+	}
+
+	// mode permission butuh role mapping yang lengkap sebelum menyentuh SharePoint
+	if *syncPermissionsFlag || *auditPermissionsFlag || *syncGroupsFlag || *exportAccessFlag {
+		if err := initRoleMapping(db); err != nil {
+			log.Fatalf("❌ Role mapping tidak valid: %v", err)
+		}
+	}
+
 	switch {
 	case *singleFile != "":
 		if err := uploadFile(db, *singleFile); err != nil {
@@ -124,6 +218,78 @@ func main() {
 		}
 	case *folderPath != "":
 		uploadFolder(db, *folderPath)
+	case *createSPFoldersFlag:
+		if err := createSharePointFolders(db, defaultFolderPath()); err != nil {
+			log.Fatalf("❌ Pembuatan folder SharePoint gagal: %v", err)
+		}
+	case *syncPermissionsFlag:
+		if err := syncPermissions(db, defaultFolderPath(), *dryRunFlag); err != nil {
+			log.Fatalf("❌ Sinkronisasi permission gagal: %v", err)
+		}
+	case *auditPermissionsFlag:
+		if err := auditPermissions(db, defaultFolderPath()); err != nil {
+			log.Fatalf("❌ Audit permission gagal: %v", err)
+		}
+	case *exportAccessFlag:
+		scope := types.AccessScope{
+			Profiles: parseProfiles(*profilesFlag),
+			FolderID: *folderIDFlag,
+			Email:    strings.ToLower(strings.TrimSpace(*emailFlag)),
+			All:      *allAccessFlag,
+		}
+		if err := exportAccess(db, scope, *accessOutFlag, ""); err != nil {
+			log.Fatalf("❌ Ekspor akses gagal: %v", err)
+		}
+	case *resolveUsersFlag:
+		if err := resolveAccessUsers(*accessOutFlag); err != nil {
+			log.Fatalf("❌ Resolve user gagal: %v", err)
+		}
+	case *syncGroupsFlag:
+		if err := syncProfileGroups(db, defaultFolderPath(), *groupTypeFlag, *dryRunFlag); err != nil {
+			log.Fatalf("❌ Sinkronisasi group gagal: %v", err)
+		}
+	case *revokeInactiveFlag:
+		if err := revokeInactive(db, defaultFolderPath(), *dryRunFlag); err != nil {
+			log.Fatalf("❌ Rekonsiliasi user nonaktif gagal: %v", err)
+		}
+	case *verifyLocalFlag:
+		if err := verifyLocal(exportFolder); err != nil {
+			log.Fatalf("❌ Verifikasi lokal gagal: %v", err)
+		}
+	case *resumeFlag != "":
+		info, err := manifest.LoadInfo(*resumeFlag)
+		if err != nil {
+			log.Fatalf("❌ Resume gagal: %v", err)
+		}
+		conflict, err := sharepoint.ParseConflictPolicy(info.SPConflict)
+		if err != nil {
+			log.Fatalf("❌ Resume gagal: %v", err)
+		}
+		if info.WithUpload || info.OnlyUpload || info.DirectSP {
+			initLibraryURL()
+		}
+		opts := extractOptions{
+			runID:                info.RunID,
+			resume:               true,
+			folderPath:           info.FolderPath,
+			start:                info.Start,
+			end:                  info.End,
+			withUploadSharepoint: info.WithUpload,
+			onlyUploadSharepoint: info.OnlyUpload,
+			noReplace:            info.NoReplace,
+			workers:              *workersFlag,
+			pipeline:             info.Pipeline,
+			deleteAfterUpload:    info.DeleteAfterUpload,
+			maxDiskMB:            info.MaxDiskMB,
+			directSharepoint:     info.DirectSP,
+			allVersions:          info.AllVersions,
+			fieldMapping:         info.FieldMapping,
+			quickXor:             info.QuickXor,
+			spConflict:           conflict,
+		}
+		if err := extractAllFiles(db, opts); err != nil {
+			log.Fatalf("❌ Ekstrak gagal: %v", err)
+		}
 	case *extractFlag:
 		start := 0
 		end := 0 // Default values
@@ -134,7 +300,28 @@ func main() {
 		if *endFlag > 0 {
 			end = *endFlag
 		}
-		if err := extractAllFiles(db, *withUploadSharepointFlag, start, end, *noReplace, *onlyUploadSharepoint); err != nil {
+		conflict, err := sharepoint.ParseConflictPolicy(*spConflictFlag)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		opts := extractOptions{
+			runID:                manifest.NewRunID(),
+			start:                start,
+			end:                  end,
+			withUploadSharepoint: *withUploadSharepointFlag,
+			onlyUploadSharepoint: *onlyUploadSharepoint,
+			noReplace:            *noReplace,
+			workers:              *workersFlag,
+			pipeline:             *pipelineFlag,
+			deleteAfterUpload:    *deleteAfterUploadFlag,
+			maxDiskMB:            *maxDiskFlag,
+			directSharepoint:     *directSharepointFlag,
+			allVersions:          *allVersionsFlag,
+			fieldMapping:         *fieldMappingFlag,
+			quickXor:             *quickXorFlag,
+			spConflict:           conflict,
+		}
+		if err := extractAllFiles(db, opts); err != nil {
 			log.Fatalf("❌ Ekstrak gagal: %v", err)
 		}
 	default:
@@ -142,6 +329,29 @@ func main() {
 	}
 }
 
+// defaultFolderPath mengembalikan filter path folder dari FOLDER_PATH.
+func defaultFolderPath() string {
+	if p := os.Getenv("FOLDER_PATH"); p != "" {
+		return p
+	}
+	return "REPOSITORY/MMS GROUP INDONESIA/IT/IT Development"
+}
+
+// initLibraryURL mengisi URL library untuk kolom sharepoint_url tabel pemetaan kalau
+// SP_LIBRARY_URL tidak diset. Hanya dipanggil di mode yang mengakses SharePoint; kalau gagal
+// kolom itu dikosongkan.
+func initLibraryURL() {
+	if os.Getenv("SP_LIBRARY_URL") != "" {
+		return
+	}
+	u, err := sharepoint.GetDriveWebURL()
+	if err != nil {
+		log.Printf("⚠️ URL library SharePoint tidak diketahui, kolom sharepoint_url dikosongkan: %v\n", err)
+		return
+	}
+	spPaths.SetLibraryURL(u)
+}
+
 func loadEnv(env string) {
 	var envFile string
 
@@ -193,25 +403,98 @@ func uploadFolder(db *sql.DB, folder string) {
 	}
 }
 
-func loadLargeObject(db *sql.DB, oid uint32) ([]byte, error) {
-	conn, err := db.Conn(context.Background())
+// fileChecksums adalah hash isi file yang dihitung saat file ditulis atau dibaca ulang.
+type fileChecksums struct {
+	sha256   string
+	quickXor string // kosong kalau QuickXorHash tidak diminta
+}
+
+// checksumWriter menggabungkan SHA-256 dan (opsional) QuickXorHash dalam satu io.Writer.
+type checksumWriter struct {
+	sha, qx hash.Hash
+}
+
+func newChecksumWriter(quickXor bool) *checksumWriter {
+	c := &checksumWriter{sha: sha256.New()}
+	if quickXor {
+		c.qx = sharepoint.NewQuickXorHash()
+	}
+	return c
+}
+
+func (c *checksumWriter) Write(p []byte) (int, error) {
+	c.sha.Write(p)
+	if c.qx != nil {
+		c.qx.Write(p)
+	}
+	return len(p), nil
+}
+
+func (c *checksumWriter) sums() fileChecksums {
+	sums := fileChecksums{sha256: hex.EncodeToString(c.sha.Sum(nil))}
+	if c.qx != nil {
+		sums.quickXor = sharepoint.QuickXorHashString(c.qx)
+	}
+	return sums
+}
+
+// streamToFile menulis hasil copyFn ke path lewat file .part lalu rename,
+// sambil menghitung SHA-256 (dan QuickXorHash kalau quickXor) tanpa menampung isi file di memori.
+func streamToFile(path string, quickXor bool, copyFn func(w io.Writer) (int64, error)) (int64, fileChecksums, error) {
+	tmpPath := path + ".part"
+	f, err := os.Create(tmpPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get db conn: %w", err)
+		return 0, fileChecksums{}, err
 	}
-	defer conn.Close()
 
-	var data []byte
-	row := conn.QueryRowContext(context.Background(), "SELECT lo_get($1)", oid)
-	if err := row.Scan(&data); err != nil {
-		return nil, fmt.Errorf("failed to read large object: %w", err)
+	h := newChecksumWriter(quickXor)
+	n, err := copyFn(io.MultiWriter(f, h))
+	if err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return n, fileChecksums{}, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return n, fileChecksums{}, err
 	}
-	return data, nil
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return n, fileChecksums{}, err
+	}
+
+	return n, h.sums(), nil
 }
 
 type extracted struct {
 	localPath, sharePointPath string
 	sizeMB                    float64
 	isDummy                   bool
+	documentID                string
+	version                   int64
+	reserved                  int64     // byte disk budget yang dipegang selama file ada di disk
+	modifiedAt                time.Time // tanggal modified asli di Teradocu
+	createdAt                 time.Time // tanggal dibuat asli di Teradocu
+}
+
+// extractOptions adalah parameter satu run --extract; disimpan ke manifest supaya bisa di-resume.
+type extractOptions struct {
+	runID                string
+	resume               bool
+	folderPath           string
+	start, end           int
+	withUploadSharepoint bool
+	onlyUploadSharepoint bool
+	noReplace            bool
+	workers              int
+	pipeline             bool
+	deleteAfterUpload    bool
+	maxDiskMB            int64
+	directSharepoint     bool
+	allVersions          bool
+	fieldMapping         string
+	quickXor             bool
+	spConflict           sharepoint.ConflictPolicy
 }
 
 func extractAllFolderPath(db *sql.DB) error {
@@ -248,7 +531,7 @@ func extractAllFolderPath(db *sql.DB) error {
 
 }
 
-func extractAllFiles(db *sql.DB, withUploadSharepoint bool, start int, end int, noReplace bool, onlyUploadSharepoint bool) error {
+func extractAllFiles(db *sql.DB, opts extractOptions) error {
 	datetime := time.Now().Format("2006-01-02T15-04-05")
 	logPath := "logs/extraction_log_" + datetime + ".txt"
 	_ = os.MkdirAll("logs", os.ModePerm)
@@ -262,6 +545,11 @@ func extractAllFiles(db *sql.DB, withUploadSharepoint bool, start int, end int,
 	log.SetOutput(io.MultiWriter(os.Stdout, logFile))
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
+	withUploadSharepoint := opts.withUploadSharepoint
+	onlyUploadSharepoint := opts.onlyUploadSharepoint
+	noReplace := opts.noReplace
+	start, end := opts.start, opts.end
+
 	if onlyUploadSharepoint {
 		noReplace = true
 	}
@@ -275,14 +563,58 @@ func extractAllFiles(db *sql.DB, withUploadSharepoint bool, start int, end int,
 		}
 	}
 
-	folderPath := os.Getenv("FOLDER_PATH")
+	folderPath := opts.folderPath
 	if folderPath == "" {
-		folderPath = "REPOSITORY/MMS GROUP INDONESIA/IT/IT Development"
+		folderPath = defaultFolderPath()
+	}
+
+	store, err := manifest.Open(opts.runID)
+	if err != nil {
+		return fmt.Errorf("gagal membuka manifest: %w", err)
+	}
+	defer store.Close()
+
+	if !opts.resume {
+		err := manifest.SaveInfo(manifest.RunInfo{
+			RunID:      opts.runID,
+			CreatedAt:  time.Now(),
+			FolderPath: folderPath,
+			Start:      start,
+			End:        end,
+			WithUpload: withUploadSharepoint,
+			OnlyUpload: onlyUploadSharepoint,
+			NoReplace:  opts.noReplace,
+			ExportPath: exportFolder,
+
+			Pipeline:          opts.pipeline,
+			DeleteAfterUpload: opts.deleteAfterUpload,
+			MaxDiskMB:         opts.maxDiskMB,
+			DirectSP:          opts.directSharepoint,
+			AllVersions:       opts.allVersions,
+			FieldMapping:      opts.fieldMapping,
+			QuickXor:          opts.quickXor,
+			SPConflict:        string(opts.spConflict),
+		})
+		if err != nil {
+			return fmt.Errorf("gagal menyimpan manifest run: %w", err)
+		}
+		log.Printf("🆔 Run ID: %s (lanjutkan dengan --resume %s)\n", opts.runID, opts.runID)
+	} else {
+		log.Printf("🔁 Resume run %s: %v\n", opts.runID, store.Counts())
+	}
+
+	// Create folder all first (tidak perlu untuk --direct-sp karena tidak ada file lokal)
+	if !opts.directSharepoint {
+		if err := extractAllFolderPath(db); err != nil {
+			return fmt.Errorf("gagal membuat folder: %w", err)
+		}
 	}
 
-	// Create folder all first
-	if err := extractAllFolderPath(db); err != nil {
-		return fmt.Errorf("gagal membuat folder: %w", err)
+	// --all-versions tidak memfilter ke versi terakhir; urutan document_id, version
+	// dipakai supaya versi lama di-upload lebih dulu ke item SharePoint yang sama
+	versionFilter := `JOIN latest_version lv ON lv.document_id = doc_bl.document_id AND lv.version = doc_bl.version`
+	if opts.allVersions {
+		versionFilter = ""
 	}
 
 	query := `
@@ -291,14 +623,16 @@ func extractAllFiles(db *sql.DB, withUploadSharepoint bool, start int, end int,
 		FROM teradocu.document_binary_large
 		GROUP BY document_id
 	)
-	SELECT doc_meta.filename, doc_meta.mime_type, doc_meta.file_type,
-		fl.fullpath, doc_bl.pdf, doc_bl.binary, fl.id, doc_meta.size
+	SELECT doc_bl.document_id, doc_bl.version, doc_meta.filename, doc_meta.mime_type, doc_meta.file_type,
+		fl.fullpath, COALESCE(octet_length(doc_bl.pdf), 0), doc_bl.binary, fl.id, doc_meta.size, doc_meta.modified_date,
+		doc_meta.created_date
 	FROM teradocu.document_binary_large doc_bl
-	JOIN latest_version lv ON lv.document_id = doc_bl.document_id AND lv.version = doc_bl.version
+	` + versionFilter + `
 	INNER JOIN teradocu.document doc ON doc.id = doc_bl.document_id
-	INNER JOIN teradocu.document_metadata doc_meta ON doc.id = doc_meta.document_id AND lv.version = doc_meta.version
+	INNER JOIN teradocu.document_metadata doc_meta ON doc.id = doc_meta.document_id AND doc_bl.version = doc_meta.version
 	INNER JOIN teradocu.folder fl ON doc.folder_id = fl.id
-	WHERE doc.deleted_date is null AND fl.fullpath ILIKE '%` + folderPath + `%'`
+	WHERE doc.deleted_date is null AND fl.fullpath ILIKE '%` + folderPath + `%'
+	ORDER BY doc_bl.document_id, doc_bl.version`
 
 	var rows *sql.Rows
 	if end > 0 {
@@ -314,182 +648,386 @@ func extractAllFiles(db *sql.DB, withUploadSharepoint bool, start int, end int,
 
 	writer := (*csv.Writer)(nil)
 	if !onlyUploadSharepoint {
-		metaFile, err := os.Create("extracted_metadata.csv")
+		// resume menambah ke CSV yang sudah ada, bukan menimpa
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if opts.resume {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		metaFile, err := os.OpenFile("extracted_metadata.csv", flags, 0644)
 		if err != nil {
 			return fmt.Errorf("failed to create metadata CSV: %w", err)
 		}
 		defer metaFile.Close()
 		writer = csv.NewWriter(metaFile)
 		defer writer.Flush()
-		writer.Write([]string{"file_name", "file_type", "mime_type", "full_path", "saved_path", "size_mb"})
+		if !opts.resume {
+			writer.Write([]string{"file_name", "file_type", "mime_type", "full_path", "saved_path", "size_mb", "version", "modified_date", "sha256", "quick_xor_hash"})
+		}
 	}
 
 	var (
-		extractedFiles []extracted
-		totalSizeMB    float64
-		count          int32
-		timestamp      = "" //time.Now().Format("2006-01-02T15-04-05")
-		startTime      = time.Now()
+		uploadGroups [][]extracted
+		totalSizeMB  float64
+		count        int32
+		resumed      int32
+		timestamp    = "" //time.Now().Format("2006-01-02T15-04-05")
+		startTime    = time.Now()
 	)
 
-	for rows.Next() {
-		var (
-			fileName, mimeType, fileType, fullPath string
-			pdfData                                []byte
-			binaryOid                              sql.NullInt64
-			folderId                               string
-			metaSize                               sql.NullInt64
-		)
+	// --direct-sp meng-upload di worker ekstraksi, jadi fase upload terpisah tidak dipakai
+	withUpload := (withUploadSharepoint || onlyUploadSharepoint) && !opts.directSharepoint
+	pipelined := opts.pipeline && withUpload
 
-		if err := rows.Scan(&fileName, &mimeType, &fileType, &fullPath, &pdfData, &binaryOid, &folderId, &metaSize); err != nil {
-			log.Printf("❌ Failed to scan row: %v\n", err)
-			continue
+	if opts.allVersions {
+		if opts.directSharepoint {
+			return fmt.Errorf("--all-versions belum bisa dipakai bersama --direct-sp")
+		}
+		if pipelined && opts.maxDiskMB > 0 {
+			return fmt.Errorf("--all-versions tidak bisa dipakai bersama --max-disk-mb")
 		}
+	}
+	// run lama (--resume) bisa tersimpan dengan kombinasi yang sekarang ditolak saat parsing flag
+	if err := checkDiskBudgetFlags(pipelined, opts.deleteAfterUpload, opts.maxDiskMB); err != nil {
+		return err
+	}
 
-		var fileData []byte
-		var sizeMB float64
-		isDummy := false
-		outputPath := ""
+	var budget *diskBudget
+	if pipelined {
+		budget = newDiskBudget(opts.maxDiskMB)
+		log.Printf("🚰 Pipeline extract+upload aktif (batas disk: %d MB, hapus setelah upload: %v)\n", opts.maxDiskMB, opts.deleteAfterUpload)
+	}
 
-		if onlyUploadSharepoint {
-			if metaSize.Valid {
-				sizeMB = float64(metaSize.Int64) / (1024 * 1024)
-			} else {
-				log.Println("⚠️ Skipping: no size metadata")
-				continue
-			}
-			outputPath = filepath.Join(exportFolder, filepath.FromSlash(fullPath), sanitizeFileName(fileName))
-			isDummy = true
+	fields, err := newFieldUpdater(db, store, opts.fieldMapping)
+	if err != nil {
+		return err
+	}
+
+	up := &uploader{
+		store:             store,
+		budget:            budget,
+		deleteAfterUpload: opts.deleteAfterUpload,
+		fields:            fields,
+		conflict:          opts.spConflict,
+	}
+
+	workers := extractWorkerCount(opts.workers)
+	tuneDBPool(db, workers)
+	log.Printf("👷 Extract workers: %d\n", workers)
+
+	x := &extractor{
+		db:         db,
+		store:      store,
+		noReplace:  noReplace,
+		onlyUpload: onlyUploadSharepoint,
+		direct:     opts.directSharepoint,
+		versioned:  opts.allVersions,
+		quickXor:   opts.quickXor,
+		conflict:   opts.spConflict,
+		timestamp:  timestamp,
+		budget:     budget,
+		fields:     fields,
+	}
+
+	jobs := make(chan extractJob, workers*2)
+	produceErrc := make(chan error, 1)
+	go func() {
+		produceErrc <- produceExtractJobs(rows, jobs)
+	}()
+
+	results := x.run(jobs, workers)
+
+	progressDone := make(chan struct{})
+	go x.reportProgress(progressDone, 30*time.Second)
+
+	var (
+		uploadQueue chan []extracted
+		uploadWg    *sync.WaitGroup
+		uploadBar   *progressbar.ProgressBar
+		uploadStart time.Time
+		queued      int
+		group       []extracted
+	)
+	if pipelined {
+		uploadQueue = make(chan []extracted, uploadWorkers*2)
+		uploadBar = progressbar.Default(-1, "Uploading")
+		uploadStart = time.Now()
+		uploadWg = up.run(uploadQueue, uploadWorkers, uploadBar)
+	}
+
+	// submit meneruskan satu grup file (semua versi satu dokumen) ke upload
+	submit := func(g []extracted) {
+		if len(g) == 0 {
+			return
+		}
+		if pipelined {
+			queued += len(g)
+			uploadQueue <- g
 		} else {
-			if mimeType == "application/pdf" && len(pdfData) > 0 {
-				fileData = pdfData
-				sizeMB = float64(len(fileData)) / (1024 * 1024)
-			} else if binaryOid.Valid {
-				fileData, err = loadLargeObject(db, uint32(binaryOid.Int64))
-				if err != nil {
-					log.Printf("❌ Failed to load LO %d: %v\n", binaryOid.Int64, err)
-					continue
-				}
-				sizeMB = float64(len(fileData)) / (1024 * 1024)
-			} else {
-				log.Println("⚠️  No valid content")
-				continue
-			}
+			uploadGroups = append(uploadGroups, g)
+		}
+	}
 
-			outputPath = filepath.Join(exportFolder, filepath.FromSlash(fullPath), sanitizeFileName(fileName))
-			_ = os.MkdirAll(filepath.Dir(outputPath), os.ModePerm)
+	// enqueue mengumpulkan versi per dokumen pada --all-versions; hasil query sudah urut
+	// per document_id, version sehingga grup selesai saat document_id berganti
+	enqueue := func(f extracted) {
+		if !opts.allVersions {
+			submit([]extracted{f})
+			return
+		}
+		if len(group) > 0 && group[0].documentID != f.documentID {
+			submit(group)
+			group = nil
+		}
+		group = append(group, f)
+	}
 
-			if noReplace {
-				if _, err := os.Stat(outputPath); err == nil {
-					log.Printf("⚠️ Skipping (exists): %s\n", outputPath)
-					continue
-				}
+	// dispatch dipanggil begitu hasil worker datang: manifest dicatat dan, pada mode pipeline,
+	// file langsung diteruskan ke upload supaya disk budget bisa dibebaskan tanpa menunggu urutan.
+	dispatch := func(r extractResult) {
+		job := r.job
+
+		if r.status == statusExtracted && !r.file.isDummy {
+			err := store.Update(job.documentID, job.version, func(e *manifest.Entry) {
+				e.State = manifest.StateExtracted
+				e.FileName = job.fileName
+				e.LocalPath = r.file.localPath
+				e.SPPath = r.file.sharePointPath
+				e.SizeBytes = r.sizeBytes
+				e.SHA256 = r.checksums.sha256
+				e.QuickXorHash = r.checksums.quickXor
+				e.ModifiedAt = r.file.modifiedAt
+				e.Error = ""
+				e.ExtractedAt = time.Now()
+			})
+			if err != nil {
+				log.Printf("⚠️ Gagal menulis manifest: %v\n", err)
 			}
+		}
 
-			if err := os.WriteFile(outputPath, fileData, 0644); err != nil {
-				log.Printf("❌ Failed to save file %s: %v\n", outputPath, err)
-				continue
+		if r.status == statusUploaded {
+			err := store.Update(job.documentID, job.version, func(e *manifest.Entry) {
+				e.State = manifest.StateUploaded
+				e.FileName = job.fileName
+				e.SPPath = r.file.sharePointPath
+				e.SizeBytes = r.sizeBytes
+				e.ModifiedAt = r.file.modifiedAt
+				setUploadHashes(e, r.item)
+				e.Error = ""
+				e.UploadedAt = time.Now()
+			})
+			if err != nil {
+				log.Printf("⚠️ Gagal menulis manifest: %v\n", err)
 			}
+			// setelah StateUploaded tercatat, supaya error kolom tidak tertimpa
+			x.fields.queue(r.file, r.item.ID)
 		}
 
-		count++
-		totalSizeMB += sizeMB
+		if (r.status == statusExtracted || r.status == statusResumed) && !opts.allVersions {
+			enqueue(r.file)
+		}
+	}
+
+	// hasil worker bisa datang tidak berurutan; CSV dan log ditulis sesuai urutan query
+	pending := make(map[int]extractResult)
+	next := 0
+
+	handle := func(r extractResult) {
+		job := r.job
+
+		if (r.status == statusExtracted || r.status == statusResumed) && opts.allVersions {
+			enqueue(r.file)
+		}
+
+		switch r.status {
+		case statusDone, statusResumed:
+			resumed++
+			return
+		case statusIgnored:
+			return
+		case statusFailed:
+			log.Printf("❌ Failed to extract %s: %v\n", job.fileName, r.err)
+			if r.item != nil {
+				recordUploadFailed(store, job.documentID, job.version, r.item, r.err)
+			} else {
+				recordFailed(store, job.documentID, job.version, r.err)
+			}
+			return
+		case statusSkipped:
+			if r.item != nil {
+				// --direct-sp dengan --sp-conflict skip/newer: item SharePoint dipertahankan
+				log.Printf("⏭️ Skipping (%s): %s\n", r.item.Skipped, r.file.sharePointPath)
+			} else {
+				log.Printf("⚠️ Skipping (exists): %s\n", r.file.localPath)
+			}
+			_ = store.Update(job.documentID, job.version, func(e *manifest.Entry) {
+				e.State = manifest.StateSkipped
+				e.FileName = job.fileName
+				e.LocalPath = r.file.localPath
+				if r.item != nil {
+					e.SPPath = r.file.sharePointPath
+					setUploadHashes(e, r.item)
+				}
+			})
+			return
+		}
 
-		spPath := fmt.Sprintf("%s/%s", timestamp, strings.TrimPrefix(outputPath, exportFolder+string(os.PathSeparator)))
-		extractedFiles = append(extractedFiles, extracted{outputPath, spPath, sizeMB, isDummy})
+		count++
+		totalSizeMB += r.file.sizeMB
 
+		savedPath := r.file.localPath
+		if r.status == statusUploaded {
+			savedPath = "sharepoint:" + r.file.sharePointPath
+		}
 		if writer != nil && !onlyUploadSharepoint {
-			writer.Write([]string{fileName, fileType, mimeType, fullPath, outputPath, fmt.Sprintf("%.2f", sizeMB)})
+			writer.Write([]string{job.fileName, job.fileType, job.mimeType, job.fullPath, savedPath, fmt.Sprintf("%.2f", r.file.sizeMB),
+				strconv.FormatInt(job.version, 10), formatModified(job.modifiedAt), r.checksums.sha256, r.checksums.quickXor})
 		}
-		log.Printf("📄 [%d] %s (%.2f MB)\n", count, fileName, sizeMB)
+		log.Printf("📄 [%d] (w%d) %s (%.2f MB)\n", count, r.worker+1, job.fileName, r.file.sizeMB)
 	}
 
+	for r := range results {
+		dispatch(r)
+
+		pending[r.job.seq] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			handle(r)
+		}
+	}
+	close(progressDone)
+	submit(group)
+
+	if err := <-produceErrc; err != nil {
+		log.Printf("❌ Kesalahan saat membaca baris: %v\n", err)
+	}
+	x.logWorkerStats()
+
 	log.Printf("\n✅ Extracted from path: %s\n", folderPath)
 	log.Printf("\n✅ Extracted %d files, total %.2f MB\n", count, totalSizeMB)
 	log.Printf("✅ Extracted %d files, %.2f MB, time: %s\n", count, totalSizeMB, time.Since(startTime))
 	log.Printf("\n✅ Extraction completed!\n")
 	log.Printf("📂 Total files extracted: %d\n", count)
+	log.Printf("♻️  Total files resumed from manifest: %d\n", resumed)
 	log.Printf("📦 Total size extracted: %.2f MB\n", totalSizeMB)
 	log.Printf("⏱️  Extraction time: %s\n", time.Since(startTime))
 
-	if withUploadSharepoint || onlyUploadSharepoint {
-		log.Println("\n🚀 Starting SharePoint upload...")
-		uploadStart := time.Now()
-		var uploadCount int32
-		var failedFirstPass []extracted
-		var failedAlready []string
-		var failedFinal []string
-		var wg sync.WaitGroup
-		sem := make(chan struct{}, 5)
-		bar := progressbar.Default(int64(len(extractedFiles)), "Uploading")
-
-		// Pass 1 - Upload semua file
-		for _, f := range extractedFiles {
-			wg.Add(1)
-			sem <- struct{}{}
-			go func(f extracted) {
-				defer wg.Done()
-				defer func() { <-sem }()
-				defer bar.Add(1)
-
-				_, err := sharepoint.UploadFileChunkedResumeV2(f.localPath, f.sharePointPath)
-				if err != nil {
-					if strings.Contains(err.Error(), "409") {
-						failedAlready = append(failedAlready, f.localPath)
-						log.Printf("❌ Upload gagal (409): %s", f.localPath)
-					} else {
-						failedFirstPass = append(failedFirstPass, f)
-						log.Printf("❌ Upload gagal: %s (%v)", f.localPath, err)
-					}
-				} else {
-					atomic.AddInt32(&uploadCount, 1)
-					log.Printf("✔️ Uploaded: %s (%.2f MB)", filepath.Base(f.localPath), f.sizeMB)
-				}
-			}(f)
+	if withUpload {
+		if !pipelined {
+			log.Println("\n🚀 Starting SharePoint upload...")
+			uploadStart = time.Now()
+			uploadQueue = make(chan []extracted)
+			uploadBar = progressbar.Default(int64(countFiles(uploadGroups)), "Uploading")
+			uploadWg = up.run(uploadQueue, uploadWorkers, uploadBar)
+
+			// Pass 1 - Upload semua file
+			for _, g := range uploadGroups {
+				queued += len(g)
+				uploadQueue <- g
+			}
 		}
-		wg.Wait()
-		bar.Finish()
+		close(uploadQueue)
+		uploadWg.Wait()
+		uploadBar.Finish()
 
 		// Pass 2 - Retry untuk file gagal di Pass 1
-		if len(failedFirstPass) > 0 {
-			log.Printf("\n🔄 Retry upload untuk %d file yang gagal...", len(failedFirstPass))
-			barRetry := progressbar.Default(int64(len(failedFirstPass)), "Retrying")
-			var failedFinal []string
-
-			for _, f := range failedFirstPass {
-				_, err := sharepoint.UploadFileChunkedResumeV2(f.localPath, f.sharePointPath)
-				if err != nil {
-					failedFinal = append(failedFinal, f.localPath)
-					log.Printf("❌ Retry gagal: %s (%v)", f.localPath, err)
-				} else {
-					atomic.AddInt32(&uploadCount, 1)
-					log.Printf("✔️ Retry sukses: %s (%.2f MB)", filepath.Base(f.localPath), f.sizeMB)
-				}
-				barRetry.Add(1)
-			}
-			barRetry.Finish()
-
-			if len(failedFinal) > 0 {
-				_ = os.WriteFile("upload_failed_final.txt", []byte(strings.Join(failedFinal, "\n")), 0644)
-				log.Printf("\n🚨 Masih ada %d file gagal setelah retry, cek upload_failed_final.txt", len(failedFinal))
-			} else {
-				log.Println("\n🎉 Semua file berhasil di-upload setelah retry!")
-			}
-		}
+		failedFinal := up.retryFailed()
 
 		log.Printf("\n✅ Upload from path: %s\n", folderPath)
-		log.Printf("\n📤 Upload selesai: %d/%d berhasil", uploadCount, len(extractedFiles))
+		log.Printf("\n📤 Upload selesai: %d/%d berhasil", up.count, queued)
 		log.Printf("⏱️  Durasi upload: %s\n", time.Since(uploadStart))
-		log.Printf("📂 Total files uploaded: %d\n", uploadCount)
-		log.Printf("📦 Total files failed: %d\n", len(failedFirstPass))
-		log.Printf("📦 Total files failed (already): %d\n", len(failedAlready))
+		log.Printf("📂 Total files uploaded: %d\n", up.count)
+		log.Printf("⏭️  Total files skipped (--sp-conflict %s): %d\n", opts.spConflict, up.skipped)
+		log.Printf("📦 Total files failed: %d\n", countFiles(up.failed))
+		log.Printf("📦 Total files failed (already): %d\n", len(up.already))
 		log.Printf("📦 Total files failed (final): %d\n", len(failedFinal))
 		log.Printf("📦 Total size uploaded: %.2f MB\n", totalSizeMB)
 	}
 
+	fields.flush()
+
+	log.Printf("🆔 Manifest run %s: %v\n", opts.runID, store.Counts())
+
 	return nil
 }
 
+// formatModified memformat tanggal modified untuk CSV; kosong kalau tidak diketahui.
+func formatModified(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// recordFailed mencatat kegagalan ke manifest tanpa menghapus status ekstraksi sebelumnya.
+func recordFailed(store *manifest.Store, documentID string, version int64, cause error) {
+	err := store.Update(documentID, version, func(e *manifest.Entry) {
+		e.State = manifest.StateFailed
+		e.Error = cause.Error()
+	})
+	if err != nil {
+		log.Printf("⚠️ Gagal menulis manifest: %v\n", err)
+	}
+}
+
+// recordUploadFailed mencatat upload yang gagal setelah SharePoint mengembalikan item
+// (mis. hash tidak cocok), beserta kedua hash supaya bisa diperiksa.
+func recordUploadFailed(store *manifest.Store, documentID string, version int64, item *sharepoint.ItemResponse, cause error) {
+	err := store.Update(documentID, version, func(e *manifest.Entry) {
+		e.State = manifest.StateFailed
+		setUploadHashes(e, item)
+		e.Error = cause.Error()
+	})
+	if err != nil {
+		log.Printf("⚠️ Gagal menulis manifest: %v\n", err)
+	}
+}
+
+// recordSkippedUpload mencatat file yang tidak di-upload karena --sp-conflict skip/newer.
+func recordSkippedUpload(store *manifest.Store, f extracted, item *sharepoint.ItemResponse) {
+	err := store.Update(f.documentID, f.version, func(e *manifest.Entry) {
+		e.State = manifest.StateSkipped
+		e.SPPath = f.sharePointPath
+		if e.LocalPath == "" {
+			e.LocalPath = f.localPath
+		}
+		setUploadHashes(e, item)
+		e.Error = ""
+	})
+	if err != nil {
+		log.Printf("⚠️ Gagal menulis manifest: %v\n", err)
+	}
+}
+
+func recordUploaded(store *manifest.Store, f extracted, item *sharepoint.ItemResponse) {
+	err := store.Update(f.documentID, f.version, func(e *manifest.Entry) {
+		e.State = manifest.StateUploaded
+		e.SPPath = f.sharePointPath
+		if e.LocalPath == "" {
+			e.LocalPath = f.localPath
+		}
+		setUploadHashes(e, item)
+		e.Error = ""
+		e.UploadedAt = time.Now()
+	})
+	if err != nil {
+		log.Printf("⚠️ Gagal menulis manifest: %v\n", err)
+	}
+}
+
+// setUploadHashes menyalin QuickXorHash lokal dan SharePoint dari hasil upload ke entry manifest.
+func setUploadHashes(e *manifest.Entry, item *sharepoint.ItemResponse) {
+	if item == nil {
+		return
+	}
+	e.QuickXorHash = item.LocalQuickXorHash
+	e.SPQuickXorHash = item.QuickXorHash()
+}
+
 // Helper function for buffered file writing
 func writeFileWithBuffer(path string, data []byte) error {
 	file, err := os.Create(path)
@@ -568,7 +1106,7 @@ ORDER BY doc_bl.document_id, doc_bl.version DESC
 			continue
 		}
 
-		outputName := sanitizeFileName(fileName)
+		outputName := pathmap.SanitizeName(fileName)
 		outputPath := filepath.Join(safeFolder, outputName)
 
 		if _, err := os.Stat(outputPath); err == nil {
@@ -629,7 +1167,7 @@ ORDER BY doc_bl.document_id, doc_bl.version DESC
 		folderPath := fmt.Sprintf("%s/%s", timestamp, cleanFolderPath)
 
 		if withUploadSharepoint {
-			sharepoint.UploadFileChunkedResume(outputPath, folderPath)
+			sharepoint.UploadFileChunkedResumeV2(outputPath, folderPath)
 		}
 
 		// folderKey := fmt.Sprintf("%s/%s", timestamp, fullPath)
@@ -704,37 +1242,8 @@ func GetUserByProfileId(profile_id string, db *sql.DB) ([]types.UserFolderAccess
 		fmt.Println("❌ Gagal menjalankan query:", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	listUserFolder := []types.UserFolderAccess{}
 
-	for rows.Next() {
-		var email string
-		var profileId string
-		var folderId string
-		var filePath string
-		var folderRole string
-		if err := rows.Scan(&email, &profileId, &folderId, &filePath, &folderRole); err != nil {
-			fmt.Println("❌ Gagal membaca hasil query:", err)
-			continue
-		}
-		listUserFolder = append(listUserFolder, types.UserFolderAccess{
-			EmailAccess: types.EmailAccess{
-				Email:          email,
-				FolderRole:     folderRole,
-				SharepointRole: GetFolderRolePermission(folderRole),
-			},
-			FolderId:   folderId,
-			FolderPath: filePath,
-		})
-	}
-
-	if err := rows.Err(); err != nil {
-		fmt.Println("❌ Kesalahan saat membaca hasil query:", err)
-		return nil, err
-	}
-
-	return listUserFolder, nil
+	return scanUserFolderAccess(rows)
 }
 
 func getExtensionFromMime(mimeType string) string {
@@ -838,138 +1347,28 @@ func detectFileType(data []byte) (string, string) {
 	return "unknown", "unknown"
 }
 
-func sanitizeFileName(name string) string {
-	return strings.Map(func(r rune) rune {
-		if r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|' {
-			return '_'
-		}
-		return r
-	}, name)
-}
-
-// SaveUserFolder saves user access to JSON file.
-// If prefixAdditional is not set (""), it defaults to "pdf_exports/".
-func SaveUserFolder(db *sql.DB, prefixAdditional ...string) error {
+// SaveUserFolder menyimpan akses folder sesuai scope ke users.json.
+// prefixAdditional (opsional) ditambahkan di depan setiap folder_path.
+func SaveUserFolder(db *sql.DB, scope types.AccessScope, prefixAdditional ...string) error {
 	prefix := ""
 	if len(prefixAdditional) > 0 && prefixAdditional[0] != "" {
 		prefix = prefixAdditional[0]
 	}
 
-	listUserFolder, err := GetUserByProfileId("MMSGI_IT_DEVELOPMENT", db)
-	if err != nil {
-		log.Fatalf("❌ Gagal mendapatkan akses folder: %v", err)
-		return err
-	}
-
-	logWriterUserAccess := logs.SetLog("user_access.txt")
-	defer logs.LogFlush(logWriterUserAccess)
-
-	for _, userAccess := range listUserFolder {
-		emailAccess := userAccess.EmailAccess
-		folderPath := prefix + userAccess.FolderPath
-		fID := userAccess.FolderId
-
-		if err := SaveUser(fID, folderPath, emailAccess); err != nil {
-			log.Printf("❌ Gagal menyimpan akses folder %s: %v", fID, err)
-			return err
-		}
-	}
-
-	return nil
-}
-
-func SaveUser(folderId string, folderPath string, email types.EmailAccess) error {
-
-	type User struct {
-		FolderId    string              `json:"folder_id"`
-		FolderPath  string              `json:"folder_path"`
-		EmailAccess []types.EmailAccess `json:"email_access"`
-	}
-
-	file, err := os.OpenFile(fileUserAccess, os.O_CREATE|os.O_RDWR, 0644)
-	if err != nil {
-		return fmt.Errorf("gagal membuka file %s: %w", fileUserAccess, err)
-	}
-	defer file.Close()
-
-	var users []User
-
-	stat, _ := file.Stat()
-	if stat.Size() > 0 {
-		decoder := json.NewDecoder(file)
-		if err := decoder.Decode(&users); err != nil {
-			return fmt.Errorf("gagal decode users.json: %w", err)
-		}
-	}
-
-	found := false
-	for i, u := range users {
-		if u.FolderId == folderId {
-			// Update folder path jika berubah
-			users[i].FolderPath = folderPath
-
-			// Tambahkan email jika belum ada
-			emailExists := false
-			for _, e := range users[i].EmailAccess {
-				if e.Email == email.Email {
-					emailExists = true
-					break
-				}
-			}
-			if !emailExists {
-				users[i].EmailAccess = append(users[i].EmailAccess, email)
-			}
-
-			found = true
-			break
-		}
-	}
-	if !found {
-		users = append(users, User{
-			FolderId:    folderId,
-			FolderPath:  folderPath,
-			EmailAccess: []types.EmailAccess{email, {Email: email.Email, FolderRole: "FOLDER_VIEWER", SharepointRole: GetFolderRolePermission("FOLDER_VIEWER")}},
-		})
-	}
-
-	// Tulis ulang file
-	file.Truncate(0)
-	file.Seek(0, 0)
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(users); err != nil {
-		return fmt.Errorf("gagal encode users.json: %w", err)
-	}
-
-	fmt.Printf("✅ Berhasil menyimpan akses folder %s untuk email %s\n", folderId, email)
-	return nil
-
+	return exportAccess(db, scope, fileUserAccess, prefix)
 }
 
 func SaveListUsers() error {
-	file, err := os.Open(fileUserAccess)
+	export, err := loadAccessExport(fileUserAccess)
 	if err != nil {
-		log.Fatalf("❌ Gagal membuka file %s: %v", fileUserAccess, err)
+		log.Printf("❌ Gagal membaca %s: %v", fileUserAccess, err)
 		return err
 	}
-	defer file.Close()
-
-	type User struct {
-		FolderId    string              `json:"folder_id"`
-		FolderPath  string              `json:"folder_path"`
-		EmailAccess []types.EmailAccess `json:"email_access"`
-	}
-
-	var users []User
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&users); err != nil {
-		log.Fatalf("❌ Gagal decode %s: %v", fileUserAccess, err)
-	}
 
 	// Group unique emails only (ignore role, folder, etc.)
 	emailSet := make(map[string]struct{})
-	for _, user := range users {
-		for _, email := range user.EmailAccess {
+	for _, folder := range export.Folders {
+		for _, email := range folder.EmailAccess {
 			emailSet[email.Email] = struct{}{}
 		}
 	}
@@ -978,18 +1377,10 @@ func SaveListUsers() error {
 	for email := range emailSet {
 		emails = append(emails, email)
 	}
+	sort.Strings(emails)
 
-	outFile, err := os.Create("list_user.json")
-	if err != nil {
-		log.Fatalf("❌ Gagal membuat list_user.json: %v", err)
-		return err
-	}
-	defer outFile.Close()
-
-	encoder := json.NewEncoder(outFile)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(emails); err != nil {
-		log.Fatalf("❌ Gagal encode list_user.json: %v", err)
+	if err := writeJSONAtomic("list_user.json", emails); err != nil {
+		log.Printf("❌ Gagal menulis list_user.json: %v", err)
 		return err
 	}
 
@@ -997,32 +1388,3 @@ func SaveListUsers() error {
 
 	return nil
 }
-
-// GetFolderRoleAccessByRole returns the FolderRoleAccess with the given role, or nil if not found.
-func GetFolderRolePermission(role string) *types.FolderRoleAccess {
-	if role == "FOLDER_VIEWER" {
-		// You can add any specific logic for ROLE_VIEWER here if needed.
-		return &types.FolderRoleAccess{
-			FolderRole:     role,
-			RolePermission: "read",
-		}
-	}
-	if role == "FOLDER_CONTRIBUTOR" {
-		// You can add any specific logic for ROLE_EDITOR here if needed.
-		return &types.FolderRoleAccess{
-			FolderRole:     role,
-			RolePermission: "write",
-		}
-	}
-	if role == "FOLDER_ADMIN" {
-		// You can add any specific logic for ROLE_EDITOR here if needed.
-		return &types.FolderRoleAccess{
-			FolderRole:     role,
-			RolePermission: "owner",
-		}
-	}
-	return &types.FolderRoleAccess{
-		FolderRole:     role,
-		RolePermission: "view", // Default permission
-	}
-}
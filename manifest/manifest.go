@@ -0,0 +1,265 @@
+package manifest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const manifestDir = "data/manifest"
+
+type State string
+
+const (
+	StateExtracted State = "extracted"
+	StateUploaded  State = "uploaded"
+	StateFailed    State = "failed"
+	StateSkipped   State = "skipped"
+)
+
+// Entry adalah status terakhir satu dokumen (document_id + version) dalam satu run.
+type Entry struct {
+	DocumentID  string    `json:"document_id"`
+	Version     int64     `json:"version"`
+	State       State     `json:"state"`
+	FileName    string    `json:"file_name,omitempty"`
+	LocalPath   string    `json:"local_path,omitempty"`
+	SPPath      string    `json:"sp_path,omitempty"`
+	SizeBytes   int64     `json:"size_bytes,omitempty"`
+	SHA256      string    `json:"sha256,omitempty"`
+	ModifiedAt  time.Time `json:"modified_at,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	ExtractedAt time.Time `json:"extracted_at,omitempty"`
+	UploadedAt  time.Time `json:"uploaded_at,omitempty"`
+
+	// QuickXorHash dihitung saat ekstraksi (--quickxor) dan saat upload; SPQuickXorHash
+	// adalah file.hashes.quickXorHash dari SharePoint
+	QuickXorHash   string `json:"quick_xor_hash,omitempty"`
+	SPQuickXorHash string `json:"sp_quick_xor_hash,omitempty"`
+
+	FieldsUpdatedAt time.Time `json:"fields_updated_at,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (e Entry) IsExtracted() bool { return !e.ExtractedAt.IsZero() }
+func (e Entry) IsUploaded() bool  { return !e.UploadedAt.IsZero() }
+
+// RunInfo menyimpan parameter run supaya --resume memakai query yang sama.
+type RunInfo struct {
+	RunID      string    `json:"run_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	FolderPath string    `json:"folder_path"`
+	Start      int       `json:"start"`
+	End        int       `json:"end"`
+	WithUpload bool      `json:"with_upload"`
+	OnlyUpload bool      `json:"only_upload"`
+	NoReplace  bool      `json:"no_replace"`
+	ExportPath string    `json:"export_path"`
+
+	Pipeline          bool   `json:"pipeline"`
+	DeleteAfterUpload bool   `json:"delete_after_upload"`
+	MaxDiskMB         int64  `json:"max_disk_mb"`
+	DirectSP          bool   `json:"direct_sp"`
+	AllVersions       bool   `json:"all_versions"`
+	FieldMapping      string `json:"field_mapping,omitempty"`
+	QuickXor          bool   `json:"quick_xor,omitempty"`
+	SPConflict        string `json:"sp_conflict,omitempty"`
+}
+
+// Store adalah manifest append-only (JSON lines) di data/manifest/<run-id>.jsonl.
+// Setiap perubahan ditulis sebagai satu baris; saat dibuka ulang baris terakhir per key yang dipakai.
+type Store struct {
+	mu      sync.Mutex
+	runID   string
+	file    *os.File
+	w       *bufio.Writer
+	entries map[string]*Entry
+}
+
+func NewRunID() string {
+	return time.Now().Format("20060102-150405")
+}
+
+func key(documentID string, version int64) string {
+	return documentID + "@" + strconv.FormatInt(version, 10)
+}
+
+func entriesPath(runID string) string {
+	return filepath.Join(manifestDir, runID+".jsonl")
+}
+
+func infoPath(runID string) string {
+	return filepath.Join(manifestDir, runID+".meta.json")
+}
+
+// ListRuns mengembalikan semua run-id yang punya manifest, urut dari yang paling lama.
+func ListRuns() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(manifestDir, "*.meta.json"))
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]string, 0, len(matches))
+	for _, m := range matches {
+		runs = append(runs, strings.TrimSuffix(filepath.Base(m), ".meta.json"))
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Exists mengecek apakah run dengan id tersebut pernah dibuat.
+func Exists(runID string) bool {
+	_, err := os.Stat(infoPath(runID))
+	return err == nil
+}
+
+func SaveInfo(info RunInfo) error {
+	if err := os.MkdirAll(manifestDir, os.ModePerm); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := infoPath(info.RunID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, infoPath(info.RunID))
+}
+
+func LoadInfo(runID string) (RunInfo, error) {
+	var info RunInfo
+	b, err := os.ReadFile(infoPath(runID))
+	if err != nil {
+		return info, fmt.Errorf("run %s tidak ditemukan: %w", runID, err)
+	}
+	if err := json.Unmarshal(b, &info); err != nil {
+		return info, fmt.Errorf("gagal decode manifest run %s: %w", runID, err)
+	}
+	return info, nil
+}
+
+// Open membuka (atau membuat) manifest untuk runID dan memuat status yang sudah ada.
+func Open(runID string) (*Store, error) {
+	if err := os.MkdirAll(manifestDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	s := &Store{runID: runID, entries: make(map[string]*Entry)}
+
+	path := entriesPath(runID)
+	if f, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		for sc.Scan() {
+			var e Entry
+			// baris terakhir bisa terpotong kalau proses mati saat menulis
+			if json.Unmarshal(sc.Bytes(), &e) != nil {
+				continue
+			}
+			s.entries[key(e.DocumentID, e.Version)] = &e
+		}
+		f.Close()
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("gagal baca manifest %s: %w", path, err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = f
+	s.w = bufio.NewWriter(f)
+
+	return s, nil
+}
+
+func (s *Store) RunID() string { return s.runID }
+
+func (s *Store) Get(documentID string, version int64) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key(documentID, version)]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Update menerapkan fn ke entry (dibuat jika belum ada) lalu menulisnya ke disk.
+func (s *Store) Update(documentID string, version int64, fn func(e *Entry)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key(documentID, version)
+	e, ok := s.entries[k]
+	if !ok {
+		e = &Entry{DocumentID: documentID, Version: version}
+		s.entries[k] = e
+	}
+	fn(e)
+	e.UpdatedAt = time.Now()
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	// flush per entry supaya crash tidak menghilangkan progress
+	return s.w.Flush()
+}
+
+// Entries mengembalikan salinan semua entry, urut berdasarkan document_id dan version.
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].DocumentID != list[j].DocumentID {
+			return list[i].DocumentID < list[j].DocumentID
+		}
+		return list[i].Version < list[j].Version
+	})
+	return list
+}
+
+// Counts menghitung jumlah entry per state.
+func (s *Store) Counts() map[State]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[State]int)
+	for _, e := range s.entries {
+		counts[e.State]++
+	}
+	return counts
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	s.w.Flush()
+	s.file.Sync()
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
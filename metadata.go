@@ -0,0 +1,175 @@
+package main
+
+import (
+	"converter_blob/database"
+	"converter_blob/manifest"
+	"converter_blob/sharepoint"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// fieldUpdateBatch adalah jumlah item yang dikumpulkan sebelum kolomnya dikirim.
+const fieldUpdateBatch = 20
+
+// fieldUpdater menyalin metadata Teradocu ke kolom list SharePoint setelah file di-upload,
+// lewat ValidateUpdateListItem supaya Modified/Created hasil upload tetap dan tidak ada versi
+// tambahan. Item dikumpulkan per 20; sisa antrean dikirim oleh flush. Pada --all-versions
+// semua versi satu dokumen adalah item yang sama; yang dikirim hanya versi terbaru supaya
+// metadata versi lama tidak mendarat terakhir.
+// Nil berarti mapping tidak dikonfigurasi dan langkah ini dilewati.
+type fieldUpdater struct {
+	db      *sql.DB
+	store   *manifest.Store
+	mapping *sharepoint.FieldMapping
+
+	mu      sync.Mutex
+	pending []pendingFields
+
+	sendMu sync.Mutex       // batch dikirim satu per satu
+	sent   map[string]int64 // item id → versi terbaru yang kolomnya sudah dikirim
+}
+
+type pendingFields struct {
+	file   extracted
+	itemID string
+}
+
+// key adalah document@version; satu item bisa muncul beberapa kali dengan versi berbeda.
+func (p pendingFields) key() string {
+	return fmt.Sprintf("%s@%d", p.file.documentID, p.file.version)
+}
+
+// newFieldUpdater memuat mapping dari path (atau env SP_FIELD_MAPPING kalau path kosong).
+func newFieldUpdater(db *sql.DB, store *manifest.Store, path string) (*fieldUpdater, error) {
+	if path == "" {
+		path = os.Getenv("SP_FIELD_MAPPING")
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	mapping, err := sharepoint.LoadFieldMapping(path)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🏷️  Mapping kolom SharePoint: %s (%d kolom)\n", path, len(mapping.Fields))
+
+	return &fieldUpdater{db: db, store: store, mapping: mapping, sent: make(map[string]int64)}, nil
+}
+
+// queue menjadwalkan update kolom untuk item yang baru di-upload; begitu antrean berisi
+// fieldUpdateBatch item, antrean dikirim oleh goroutine pemanggil.
+func (fu *fieldUpdater) queue(f extracted, itemID string) {
+	if fu == nil {
+		return
+	}
+	if itemID == "" {
+		recordFields(fu.store, f, fmt.Errorf("item id kosong, kolom tidak bisa diisi"))
+		return
+	}
+
+	fu.mu.Lock()
+	fu.pending = append(fu.pending, pendingFields{file: f, itemID: itemID})
+	var batch []pendingFields
+	if len(fu.pending) >= fieldUpdateBatch {
+		batch, fu.pending = fu.pending, nil
+	}
+	fu.mu.Unlock()
+
+	fu.send(batch)
+}
+
+// flush mengirim sisa antrean; dipanggil setelah semua upload selesai.
+func (fu *fieldUpdater) flush() {
+	if fu == nil {
+		return
+	}
+	fu.mu.Lock()
+	batch := fu.pending
+	fu.pending = nil
+	fu.mu.Unlock()
+
+	fu.send(batch)
+}
+
+func (fu *fieldUpdater) send(batch []pendingFields) {
+	if len(batch) == 0 {
+		return
+	}
+
+	fu.sendMu.Lock()
+	defer fu.sendMu.Unlock()
+
+	// per item hanya versi terbaru di batch ini, dan hanya kalau belum ada versi lebih baru
+	// yang terkirim oleh batch sebelumnya
+	newest := make(map[string]pendingFields)
+	for _, p := range batch {
+		if cur, ok := newest[p.itemID]; !ok || p.file.version > cur.file.version {
+			newest[p.itemID] = p
+		}
+	}
+	superseded := func(p pendingFields) bool {
+		if newest[p.itemID].key() != p.key() {
+			return true
+		}
+		v, ok := fu.sent[p.itemID]
+		return ok && v > p.file.version
+	}
+
+	updates := make(map[string]map[string]string, len(newest))
+	errs := make(map[string]error) // key: document@version
+	for _, p := range batch {
+		if superseded(p) {
+			continue
+		}
+		row, err := database.GetDocumentMetadata(fu.db, p.file.documentID, p.file.version)
+		if err != nil {
+			errs[p.key()] = err
+			continue
+		}
+		values, err := fu.mapping.Build(row)
+		if err != nil {
+			errs[p.key()] = err
+			continue
+		}
+		updates[p.itemID] = values
+	}
+	for itemID := range updates {
+		fu.sent[itemID] = newest[itemID].file.version
+	}
+
+	for _, p := range batch {
+		if superseded(p) {
+			log.Printf("⏭️ Kolom %s versi %d dilewati, diganti metadata versi %d\n",
+				p.file.sharePointPath, p.file.version, max(newest[p.itemID].file.version, fu.sent[p.itemID]))
+			continue
+		}
+		if values, ok := updates[p.itemID]; ok {
+			// SharePoint REST tidak ikut /$batch Graph, jadi dikirim per item
+			errs[p.key()] = sharepoint.UpdateItemValues(p.itemID, values, p.file.createdAt, p.file.modifiedAt)
+		}
+		recordFields(fu.store, p.file, errs[p.key()])
+	}
+}
+
+// recordFields mencatat hasil update kolom; upload tetap dianggap berhasil walau kolom gagal.
+func recordFields(store *manifest.Store, f extracted, fieldsErr error) {
+	if fieldsErr != nil {
+		log.Printf("⚠️ Gagal update kolom SharePoint %s: %v\n", f.sharePointPath, fieldsErr)
+	}
+
+	err := store.Update(f.documentID, f.version, func(e *manifest.Entry) {
+		if fieldsErr != nil {
+			e.Error = "fields: " + fieldsErr.Error()
+			return
+		}
+		e.FieldsUpdatedAt = time.Now()
+	})
+	if err != nil {
+		log.Printf("⚠️ Gagal menulis manifest: %v\n", err)
+	}
+}
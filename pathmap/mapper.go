@@ -0,0 +1,291 @@
+package pathmap
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMapFile adalah lokasi tabel pemetaan kalau PATH_MAP_FILE tidak diset.
+const DefaultMapFile = "data/path_map.csv"
+
+// folderReserve adalah ruang yang disisakan path folder untuk nama file di dalamnya.
+const folderReserve = 40
+
+// minNameRoom adalah panjang nama terpendek yang masih layak saat path dipendekkan.
+const minNameRoom = 12
+
+var tableHeader = []string{"teradocu_path", "sharepoint_path", "sharepoint_url", "reason"}
+
+// Entry adalah satu baris tabel pemetaan.
+type Entry struct {
+	Source string // path Teradocu ("folder/sub/nama.pdf"; "/" dan "%" di nama ditulis %2F dan %25)
+	Target string // path SharePoint relatif ke root drive
+	Reason string // alasan perubahan dipisah koma, kosong kalau nama tidak berubah
+}
+
+// Mapper memetakan path Teradocu ke path SharePoint yang unik dan mencatatnya ke tabel CSV.
+// Pemetaan yang sudah ada di tabel dipakai ulang, jadi target tidak berubah antar run walaupun
+// urutan dokumen berbeda. Aman dipakai banyak goroutine. Mapper nil hanya menjalankan SanitizePath.
+type Mapper struct {
+	mu       sync.Mutex
+	file     string
+	maxPath  int
+	bySource map[string]Entry
+	byTarget map[string]string // target lowercase → source
+	out      *os.File
+	w        *csv.Writer
+
+	libraryURL string
+}
+
+// Open memuat tabel pemetaan di file (kalau ada). maxPath <= 0 berarti DefaultMaxPathLength.
+func Open(file string, maxPath int) (*Mapper, error) {
+	if maxPath <= 0 {
+		maxPath = DefaultMaxPathLength
+	}
+	m := &Mapper{
+		file:     file,
+		maxPath:  maxPath,
+		bySource: make(map[string]Entry),
+		byTarget: make(map[string]string),
+	}
+
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// baris terakhir bisa terpotong kalau proses mati saat menulis
+			log.Printf("⚠️ Tabel pemetaan %s baris %d dilewati: %v\n", file, line, err)
+			continue
+		}
+		if len(rec) < 2 || rec[0] == tableHeader[0] {
+			continue
+		}
+		e := Entry{Source: rec[0], Target: rec[1]}
+		if len(rec) > 3 {
+			e.Reason = rec[3]
+		}
+		m.bySource[e.Source] = e
+		m.byTarget[strings.ToLower(e.Target)] = e.Source
+	}
+	return m, nil
+}
+
+// OpenDefault memakai PATH_MAP_FILE (default data/path_map.csv), SP_MAX_PATH
+// (default 400; kurangi dengan panjang URL site + library kalau perlu) dan SP_LIBRARY_URL
+// (URL library untuk kolom sharepoint_url; kosong berarti lewat SetLibraryURL).
+func OpenDefault() (*Mapper, error) {
+	file := os.Getenv("PATH_MAP_FILE")
+	if file == "" {
+		file = DefaultMapFile
+	}
+	maxPath := 0
+	if v := os.Getenv("SP_MAX_PATH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("SP_MAX_PATH tidak valid: %q", v)
+		}
+		maxPath = n
+	}
+	m, err := Open(file, maxPath)
+	if err != nil {
+		return nil, err
+	}
+	m.libraryURL = strings.TrimRight(os.Getenv("SP_LIBRARY_URL"), "/")
+	return m, nil
+}
+
+// SetLibraryURL memasang URL library (webUrl root drive, mis. dari sharepoint.GetDriveWebURL)
+// untuk kolom sharepoint_url kalau SP_LIBRARY_URL tidak diset.
+func (m *Mapper) SetLibraryURL(u string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.libraryURL == "" {
+		m.libraryURL = strings.TrimRight(u, "/")
+	}
+}
+
+// urlLocked membentuk URL lengkap target: URL library + path yang di-escape per segmen.
+func (m *Mapper) urlLocked(target string) string {
+	if m.libraryURL == "" {
+		return ""
+	}
+	return m.libraryURL + "/" + EscapePath(target)
+}
+
+// Close menutup file tabel pemetaan.
+func (m *Mapper) Close() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.out == nil {
+		return nil
+	}
+	m.w.Flush()
+	err := m.w.Error()
+	if cerr := m.out.Close(); err == nil {
+		err = cerr
+	}
+	m.out, m.w = nil, nil
+	return err
+}
+
+// MapFile mengembalikan path SharePoint untuk file Teradocu name di folder. name selalu satu
+// segmen: "/" di nama file ikut diganti, bukan dianggap subfolder.
+func (m *Mapper) MapFile(folder, name string) string {
+	if m == nil {
+		return joinPath(SanitizePath(folder), SanitizeName(name))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mapLocked(cleanPath(folder), name, false)
+}
+
+// MapFolder mengembalikan path SharePoint untuk folder Teradocu. Path folder disisakan
+// ruang untuk nama file di dalamnya.
+func (m *Mapper) MapFolder(folder string) string {
+	if m == nil {
+		return SanitizePath(folder)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mapFolderLocked(cleanPath(folder))
+}
+
+func (m *Mapper) mapFolderLocked(folder string) string {
+	if folder == "" {
+		return ""
+	}
+	dir := path.Dir(folder)
+	if dir == "." {
+		dir = ""
+	}
+	return m.mapLocked(dir, path.Base(folder), true)
+}
+
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// sourceEscaper membuat key tabel tidak ambigu untuk nama file yang mengandung "/".
+var sourceEscaper = strings.NewReplacer("%", "%25", "/", "%2F")
+
+// sourceKey adalah key tabel untuk name di folder Teradocu dir (segmen dir di-escape juga).
+func sourceKey(dir, name string) string {
+	if dir == "" {
+		return sourceEscaper.Replace(name)
+	}
+	parts := strings.Split(dir, "/")
+	for i := range parts {
+		parts[i] = sourceEscaper.Replace(parts[i])
+	}
+	return strings.Join(parts, "/") + "/" + sourceEscaper.Replace(name)
+}
+
+// mapLocked memetakan name di folder Teradocu parentSource (parent dipetakan lebih dulu).
+func (m *Mapper) mapLocked(parentSource, rawName string, folder bool) string {
+	source := sourceKey(parentSource, rawName)
+	if e, ok := m.bySource[source]; ok {
+		return e.Target
+	}
+
+	parent := m.mapFolderLocked(parentSource)
+	join := func(name string) string { return joinPath(parent, name) }
+
+	name, reasons := sanitizeName(rawName)
+
+	limit := m.maxPath
+	if folder {
+		limit -= folderReserve
+	}
+	room := limit - runeLen(parent) - 1
+	if parent == "" {
+		room = limit
+	}
+	room = min(room, MaxNameLength)
+	if runeLen(name) > room {
+		if room >= minNameRoom {
+			name = withSuffix(name, source, room)
+			reasons = append(reasons, ReasonShortened)
+		} else {
+			reasons = append(reasons, ReasonTooLong)
+			log.Printf("⚠️ Path SharePoint terlalu panjang (> %d karakter): %s\n", m.maxPath, join(name))
+		}
+	}
+
+	// SharePoint tidak membedakan huruf besar/kecil: "A:B" dan "a_b" di folder yang sama bentrok
+	if owner, ok := m.byTarget[strings.ToLower(join(name))]; ok && owner != source {
+		limit := min(max(room, minNameRoom), MaxNameLength)
+		seed := source
+		for i := 1; ; i++ {
+			candidate := withSuffix(name, seed, min(limit, runeLen(name)+7))
+			if _, taken := m.byTarget[strings.ToLower(join(candidate))]; !taken {
+				log.Printf("⚠️ Nama bentrok di SharePoint: %s dan %s → %s\n", owner, source, join(candidate))
+				name = candidate
+				break
+			}
+			seed = source + "#" + strconv.Itoa(i)
+		}
+		reasons = append(reasons, ReasonCollision)
+	}
+
+	e := Entry{Source: source, Target: join(name), Reason: strings.Join(reasons, ",")}
+	m.bySource[source] = e
+	m.byTarget[strings.ToLower(e.Target)] = source
+	if err := m.writeLocked(e); err != nil {
+		log.Printf("⚠️ Gagal menulis tabel pemetaan %s: %v\n", m.file, err)
+	}
+	return e.Target
+}
+
+// writeLocked menambahkan satu baris ke tabel; file dan header dibuat saat baris pertama ditulis.
+func (m *Mapper) writeLocked(e Entry) error {
+	if m.out == nil {
+		if err := os.MkdirAll(filepath.Dir(m.file), os.ModePerm); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(m.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		m.out = f
+		m.w = csv.NewWriter(f)
+		if fi, err := f.Stat(); err == nil && fi.Size() == 0 {
+			m.w.Write(tableHeader)
+		}
+	}
+
+	m.w.Write([]string{e.Source, e.Target, m.urlLocked(e.Target), e.Reason})
+	m.w.Flush()
+	return m.w.Error()
+}
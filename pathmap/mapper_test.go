@@ -0,0 +1,148 @@
+package pathmap_test
+
+import (
+	"converter_blob/pathmap"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openMapper(t *testing.T, file string, maxPath int) *pathmap.Mapper {
+	t.Helper()
+	m, err := pathmap.Open(file, maxPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestMapperMapFile(t *testing.T) {
+	folder60 := strings.Repeat("f", 60)
+	long := strings.Repeat("a", 300) + ".pdf"
+
+	tests := []struct {
+		name         string
+		maxPath      int
+		folder, file string
+		want         string
+	}{
+		{"tidak berubah", 0, "Arsip", "dok.pdf", "Arsip/dok.pdf"},
+		{"folder dan nama disanitasi", 0, "/CON/_vti_bin/", "nama?.pdf ", "CON_/_vti-bin/nama_.pdf"},
+		{"slash di nama file", 0, "Arsip", "a/b.pdf", "Arsip/a_b.pdf"},
+		{"nama lebih dari 255", 1000, "", long, strings.Repeat("a", 244) + hash(long) + ".pdf"},
+		// folder 60 karakter + "/" menyisakan 39 karakter untuk nama file di batas 100
+		{"pas batas path", 100, folder60, strings.Repeat("x", 35) + ".pdf", folder60 + "/" + strings.Repeat("x", 35) + ".pdf"},
+		{"dipendekkan ke batas path", 100, folder60, strings.Repeat("y", 41) + ".pdf",
+			folder60 + "/" + strings.Repeat("y", 28) + hash(folder60+"/"+strings.Repeat("y", 41)+".pdf") + ".pdf"},
+	}
+	for _, tc := range tests {
+		m := openMapper(t, filepath.Join(t.TempDir(), "path_map.csv"), tc.maxPath)
+		if got := m.MapFile(tc.folder, tc.file); got != tc.want {
+			t.Errorf("%s: MapFile(%q, %q) = %q, seharusnya %q", tc.name, tc.folder, tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestMapperMapFolder(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxPath int
+		folder  string
+		want    string
+	}{
+		{"tidak berubah", 0, "Arsip/2024", "Arsip/2024"},
+		{"nama terlarang", 0, "CON/_vti_cnf", "CON_/_vti-cnf"},
+		{"titik di akhir", 0, "Arsip./Sub ", "Arsip/Sub"},
+		// folder disisakan 40 karakter untuk nama file: batasnya 100-40=60
+		{"pas sisa ruang file", 100, strings.Repeat("f", 60), strings.Repeat("f", 60)},
+		{"melebihi sisa ruang file", 100, strings.Repeat("g", 61), strings.Repeat("g", 53) + hash(strings.Repeat("g", 61))},
+	}
+	for _, tc := range tests {
+		m := openMapper(t, filepath.Join(t.TempDir(), "path_map.csv"), tc.maxPath)
+		if got := m.MapFolder(tc.folder); got != tc.want {
+			t.Errorf("%s: MapFolder(%q) = %q, seharusnya %q", tc.name, tc.folder, got, tc.want)
+		}
+	}
+}
+
+func TestMapperCollision(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second string
+		want          string
+	}{
+		{"hasil sanitasi sama", "a:b.pdf", "a?b.pdf", "Arsip/a_b" + hash("Arsip/a?b.pdf") + ".pdf"},
+		{"beda huruf besar/kecil", "Laporan.PDF", "laporan.pdf", "Arsip/laporan" + hash("Arsip/laporan.pdf") + ".pdf"},
+	}
+	for _, tc := range tests {
+		file := filepath.Join(t.TempDir(), "path_map.csv")
+		m := openMapper(t, file, 0)
+		first := m.MapFile("Arsip", tc.first)
+		if got := m.MapFile("Arsip", tc.second); got != tc.want {
+			t.Errorf("%s: %q dipetakan ke %q, seharusnya %q", tc.name, tc.second, got, tc.want)
+		}
+		if err := m.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// tabel dipakai ulang: urutan berbeda di run berikutnya tidak mengubah target
+		again := openMapper(t, file, 0)
+		if got := again.MapFile("Arsip", tc.second); got != tc.want {
+			t.Errorf("%s: setelah dibuka ulang %q dipetakan ke %q, seharusnya %q", tc.name, tc.second, got, tc.want)
+		}
+		if got := again.MapFile("Arsip", tc.first); got != first {
+			t.Errorf("%s: setelah dibuka ulang %q dipetakan ke %q, seharusnya %q", tc.name, tc.first, got, first)
+		}
+	}
+}
+
+func TestMapperTable(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data", "path_map.csv")
+	m := openMapper(t, file, 0)
+	m.SetLibraryURL("https://contoso.sharepoint.com/sites/arsip/Shared%20Documents/")
+
+	m.MapFile("Arsip", "Laporan Akhir.pdf")
+	m.MapFile("Arsip", "laporan akhir.pdf")
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suffixed := "Arsip/laporan akhir" + hash("Arsip/laporan akhir.pdf") + ".pdf"
+	want := [][]string{
+		{"teradocu_path", "sharepoint_path", "sharepoint_url", "reason"},
+		{"Arsip", "Arsip", "https://contoso.sharepoint.com/sites/arsip/Shared%20Documents/Arsip", ""},
+		{"Arsip/Laporan Akhir.pdf", "Arsip/Laporan Akhir.pdf", "https://contoso.sharepoint.com/sites/arsip/Shared%20Documents/Arsip/Laporan%20Akhir.pdf", ""},
+		{"Arsip/laporan akhir.pdf", suffixed, "https://contoso.sharepoint.com/sites/arsip/Shared%20Documents/" + pathmap.EscapePath(suffixed), pathmap.ReasonCollision},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("tabel berisi %d baris, seharusnya %d: %v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("baris %d = %q, seharusnya %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestNilMapper(t *testing.T) {
+	var m *pathmap.Mapper
+	if got := m.MapFile("/Arsip:2024/", "a/b.pdf"); got != "Arsip_2024/a_b.pdf" {
+		t.Errorf("MapFile = %q, seharusnya Arsip_2024/a_b.pdf", got)
+	}
+	if got := m.MapFolder("CON/x."); got != "CON_/x" {
+		t.Errorf("MapFolder = %q, seharusnya CON_/x", got)
+	}
+}
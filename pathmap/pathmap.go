@@ -0,0 +1,158 @@
+// Package pathmap memetakan path Teradocu ke path SharePoint dengan aturan yang sama untuk
+// file lokal, upload, folder dan permission: karakter yang tidak valid diganti "_", spasi/titik
+// di akhir nama dibuang, nama terlarang (CON, _vti_, desktop.ini, ...) diubah, dan nama/path
+// yang terlalu panjang dipendekkan dengan akhiran hash. Mapper juga mendeteksi dua path
+// Teradocu yang jatuh ke target yang sama (SharePoint tidak membedakan huruf besar/kecil)
+// dan mencatat semua pemetaan ke tabel CSV supaya run berikutnya memakai target yang sama.
+package pathmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxNameLength adalah panjang maksimal satu nama file/folder di SharePoint.
+	MaxNameLength = 255
+	// DefaultMaxPathLength adalah batas panjang path (didekode) di SharePoint Online.
+	DefaultMaxPathLength = 400
+)
+
+// invalidChars adalah gabungan karakter yang dulu diganti sanitizeFileName dan sanitizeSPName.
+const invalidChars = `"*:<>?/\|#%&{}$!'@+`
+
+// reservedNames tidak boleh dipakai sebagai nama (atau nama tanpa ekstensi) di SharePoint/Windows.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true,
+	"LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	"DESKTOP.INI": true, ".LOCK": true,
+}
+
+// Alasan perubahan nama yang dicatat di kolom reason tabel pemetaan.
+const (
+	ReasonSanitized = "sanitized" // karakter tidak valid atau spasi/titik di akhir
+	ReasonReserved  = "reserved"  // nama terlarang
+	ReasonShortened = "shortened" // nama/path terlalu panjang
+	ReasonCollision = "collision" // target sudah dipakai path Teradocu lain
+	ReasonTooLong   = "too_long"  // path tetap terlalu panjang karena folder parent
+)
+
+// SanitizeName membuat satu nama file/folder aman untuk SharePoint dan file system lokal.
+// Hasilnya stabil: SanitizeName(SanitizeName(x)) == SanitizeName(x).
+func SanitizeName(name string) string {
+	s, _ := sanitizeName(name)
+	return s
+}
+
+func sanitizeName(name string) (string, []string) {
+	var reasons []string
+
+	s := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(invalidChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	s = strings.TrimRight(strings.TrimLeft(s, " "), " .")
+	if s == "" {
+		s = "_"
+	}
+	if s != name {
+		reasons = append(reasons, ReasonSanitized)
+	}
+
+	stem := s
+	if i := strings.Index(s, "."); i > 0 {
+		stem = s[:i]
+	}
+	if reservedNames[strings.ToUpper(s)] || reservedNames[strings.ToUpper(stem)] {
+		s = stem + "_" + s[len(stem):]
+		reasons = append(reasons, ReasonReserved)
+	}
+	for {
+		i := strings.Index(strings.ToLower(s), "_vti_")
+		if i < 0 {
+			break
+		}
+		s = s[:i+4] + "-" + s[i+5:]
+		if len(reasons) == 0 || reasons[len(reasons)-1] != ReasonReserved {
+			reasons = append(reasons, ReasonReserved)
+		}
+	}
+
+	if utf8.RuneCountInString(s) > MaxNameLength {
+		s = withSuffix(s, name, MaxNameLength)
+		reasons = append(reasons, ReasonShortened)
+	}
+	return s, reasons
+}
+
+// SanitizePath menerapkan SanitizeName ke setiap segmen path ("a/b/c", tanpa "/" di depan).
+// Separator OS (mis. "\\" di Windows) dianggap "/".
+func SanitizePath(p string) string {
+	p = cleanPath(filepath.ToSlash(p))
+	if p == "" {
+		return ""
+	}
+	parts := strings.Split(p, "/")
+	for i := range parts {
+		parts[i] = SanitizeName(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+// EscapePath adalah SanitizePath yang di-URL-encode per segmen, untuk URL Graph "root:/{path}".
+func EscapePath(p string) string {
+	p = SanitizePath(p)
+	if p == "" {
+		return ""
+	}
+	parts := strings.Split(p, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+// cleanPath membuang "/" di depan/belakang dan segmen kosong; "\\" bukan separator
+// (di nama Teradocu diganti "_" oleh SanitizeName).
+func cleanPath(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+func runeLen(s string) int { return utf8.RuneCountInString(s) }
+
+// shortHash adalah 6 karakter hex SHA-256 dari seed, dipakai sebagai akhiran pembeda.
+func shortHash(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:3])
+}
+
+// withSuffix menyisipkan "~<hash seed>" sebelum ekstensi dan memotong nama supaya panjangnya
+// paling banyak limit karakter: "laporan tahunan.pdf" → "laporan tah~1a2b3c.pdf".
+func withSuffix(name, seed string, limit int) string {
+	suffix := "~" + shortHash(seed)
+
+	ext := path.Ext(name)
+	if ext == name || runeLen(ext) > 16 {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+
+	keep := limit - runeLen(suffix) - runeLen(ext)
+	if keep < 1 {
+		keep = 1
+	}
+	if runeLen(stem) > keep {
+		stem = string([]rune(stem)[:keep])
+	}
+	return strings.TrimRight(stem, " .") + suffix + ext
+}
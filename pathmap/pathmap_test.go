@@ -0,0 +1,73 @@
+package pathmap_test
+
+import (
+	"converter_blob/pathmap"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// hash adalah akhiran pembeda yang diharapkan untuk seed: "~" + 6 hex SHA-256.
+func hash(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return "~" + hex.EncodeToString(sum[:3])
+}
+
+func TestSanitizeName(t *testing.T) {
+	long := strings.Repeat("a", 300) + ".pdf"
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{"tidak berubah", "Laporan 2024.pdf", "Laporan 2024.pdf"},
+		{"karakter tidak valid", `a:b?c*d<e>f|g"h.pdf`, "a_b_c_d_e_f_g_h.pdf"},
+		{"separator", `a/b\c.pdf`, "a_b_c.pdf"},
+		{"karakter kontrol", "a\tb\x7f.pdf", "a_b_.pdf"},
+		{"titik dan spasi di akhir", "nama. . ", "nama"},
+		{"spasi di depan", "  nama.pdf", "nama.pdf"},
+		{"kosong", "", "_"},
+		{"hanya titik", "...", "_"},
+		{"CON", "CON", "CON_"},
+		{"con dengan ekstensi", "con.txt", "con_.txt"},
+		{"COM1 dengan dua ekstensi", "COM1.tar.gz", "COM1_.tar.gz"},
+		{"mirip nama terlarang", "CONTOH.pdf", "CONTOH.pdf"},
+		{"desktop.ini", "desktop.ini", "desktop_.ini"},
+		{".lock", ".lock", ".lock_"},
+		{"_vti_", "_vti_cnf", "_vti-cnf"},
+		{"_vti_ huruf besar di tengah", "a_VTI_b_vti_c", "a_VTI-b_vti-c"},
+		{"lebih dari 255", long, strings.Repeat("a", 244) + hash(long) + ".pdf"},
+		{"tepat 255", strings.Repeat("b", 251) + ".pdf", strings.Repeat("b", 251) + ".pdf"},
+	}
+	for _, tc := range tests {
+		got := pathmap.SanitizeName(tc.in)
+		if got != tc.want {
+			t.Errorf("%s: SanitizeName(%q) = %q, seharusnya %q", tc.name, tc.in, got, tc.want)
+		}
+		if n := utf8.RuneCountInString(got); n > pathmap.MaxNameLength {
+			t.Errorf("%s: panjang %d melebihi %d", tc.name, n, pathmap.MaxNameLength)
+		}
+		if again := pathmap.SanitizeName(got); again != got {
+			t.Errorf("%s: tidak stabil, SanitizeName(%q) = %q", tc.name, got, again)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"Arsip/dok.pdf", "Arsip/dok.pdf"},
+		{"/Arsip 2024//Laporan #1.pdf/", "Arsip%202024/Laporan%20_1.pdf"},
+		{"Dokumen/Übersicht.pdf", "Dokumen/%C3%9Cbersicht.pdf"},
+		{"CON/nama. ", "CON_/nama"},
+	}
+	for _, tc := range tests {
+		if got := pathmap.EscapePath(tc.in); got != tc.want {
+			t.Errorf("EscapePath(%q) = %q, seharusnya %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"converter_blob/database"
+	"converter_blob/sharepoint"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	driftMissing  = "missing"  // ada di Teradocu, tidak ada di SharePoint
+	driftExtra    = "extra"    // ada di SharePoint, tidak ada di Teradocu
+	driftMismatch = "mismatch" // ada di keduanya dengan role berbeda
+)
+
+// permissionDrift adalah satu perbedaan grant antara Teradocu dan SharePoint.
+type permissionDrift struct {
+	FolderID       string `json:"folder_id"`
+	FolderPath     string `json:"folder_path"`
+	Email          string `json:"email"`
+	Kind           string `json:"kind"`
+	TeradocuRole   string `json:"teradocu_role,omitempty"`
+	SharepointRole string `json:"sharepoint_role,omitempty"`
+	Inherited      bool   `json:"inherited"`
+}
+
+// expectedGrant adalah role yang seharusnya dimiliki satu user di folder, beserta profile
+// Teradocu yang memberikan akses itu (group --sync-groups per profile ikut dihitung).
+type expectedGrant struct {
+	role     string
+	profiles []string
+}
+
+// loadExpectedGrants menghitung role SharePoint yang seharusnya per folder (folder id → email,
+// atau siteGroupPrefix+nama untuk role yang dipetakan ke sharepoint_group) dari
+// GetFolderAccessAll, sekali untuk seluruh pathFilter.
+func loadExpectedGrants(db *sql.DB, pathFilter string) (map[string]map[string]*expectedGrant, error) {
+	rows, err := database.GetFolderAccessAll(db, pathFilter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := make(map[string]map[string]*expectedGrant)
+	for rows.Next() {
+		var (
+			email, profileID, folderID, path, folderRole string
+			parentID                                     sql.NullString
+		)
+		if err := rows.Scan(&email, &profileID, &folderID, &parentID, &path, &folderRole); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+		role, group, ok := sharepointRole(folderRole)
+		if !ok {
+			continue
+		}
+		grants, ok := folders[folderID]
+		if !ok {
+			grants = make(map[string]*expectedGrant)
+			folders[folderID] = grants
+		}
+		email = strings.ToLower(strings.TrimSpace(email))
+		if group != "" {
+			email = siteGroupPrefix + group
+		}
+		g, ok := grants[email]
+		if !ok {
+			g = &expectedGrant{}
+			grants[email] = g
+		}
+		if roleRank[role] > roleRank[g.role] {
+			g.role = role
+		}
+		if group == "" {
+			g.profiles = append(g.profiles, profileID)
+		}
+	}
+
+	return folders, rows.Err()
+}
+
+// diffFolderPermissions membandingkan grant Teradocu dengan permission SharePoint satu folder.
+// User dicocokkan lewat object id Entra ID (users, hasil resolver termasuk alias); email hanya
+// dipakai untuk user yang tidak ditemukan dan undangan yang belum diterima. Grant ke group
+// profile (--sync-groups) ikut dihitung sebagai akses anggota profile tersebut. Site group target
+// sharepoint_group dicocokkan lewat nama; site group itu di folder yang tidak mengharapkannya
+// terlapor sebagai extra, group lain diabaikan.
+func diffFolderPermissions(folderID, path string, desired map[string]*expectedGrant, users map[string]sharepoint.ResolvedUser, perms []sharepoint.Permission) []permissionDrift {
+	type actual struct {
+		role      string
+		inherited bool
+		who       string
+	}
+	better := func(cur actual, p sharepoint.Permission, who string) actual {
+		if rank := permissionRank(p); rank > roleRank[cur.role] {
+			return actual{role: rankRole(rank), inherited: p.Inherited(), who: who}
+		}
+		return cur
+	}
+
+	current := make(map[string]actual) // object id, atau email untuk undangan
+	groups := make(map[string]actual)  // nama group
+	for _, p := range perms {
+		if name := p.GroupName(); name != "" {
+			groups[name] = better(groups[name], p, name)
+			continue
+		}
+		key := p.UserID()
+		if key == "" {
+			key = p.Email()
+		}
+		if key == "" {
+			continue
+		}
+		who := p.Email()
+		if who == "" {
+			who = key
+		}
+		current[key] = better(current[key], p, who)
+	}
+
+	var drifts []permissionDrift
+	matched := make(map[string]bool)
+	matchedGroups := make(map[string]bool)
+	for email, want := range desired {
+		var cur actual
+		if name, ok := siteGroupName(email); ok {
+			cur = groups[name]
+			matchedGroups[name] = true
+		}
+		for _, key := range []string{users[email].ID, email} {
+			if c, ok := current[key]; ok && key != "" {
+				matched[key] = true
+				if roleRank[c.role] > roleRank[cur.role] {
+					cur = c
+				}
+			}
+		}
+		for _, profileID := range want.profiles {
+			if g, ok := groups[profileGroupName(profileID)]; ok && roleRank[g.role] > roleRank[cur.role] {
+				cur = g
+			}
+		}
+
+		switch {
+		case cur.role == "":
+			drifts = append(drifts, permissionDrift{folderID, path, email, driftMissing, want.role, "", false})
+		case cur.role != want.role:
+			drifts = append(drifts, permissionDrift{folderID, path, email, driftMismatch, want.role, cur.role, cur.inherited})
+		}
+	}
+	for key, cur := range current {
+		if !matched[key] {
+			drifts = append(drifts, permissionDrift{folderID, path, cur.who, driftExtra, "", cur.role, cur.inherited})
+		}
+	}
+	for name := range mappedSiteGroups() {
+		if g, ok := groups[name]; ok && !matchedGroups[name] {
+			drifts = append(drifts, permissionDrift{folderID, path, siteGroupPrefix + name, driftExtra, "", g.role, g.inherited})
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Kind != drifts[j].Kind {
+			return drifts[i].Kind < drifts[j].Kind
+		}
+		return drifts[i].Email < drifts[j].Email
+	})
+	return drifts
+}
+
+func rankRole(rank int) string {
+	for role, r := range roleRank {
+		if r == rank {
+			return role
+		}
+	}
+	return ""
+}
+
+// auditPermissions membandingkan akses per folder antara Teradocu dan SharePoint dan menulis
+// laporan CSV + JSON ke folder logs. Folder tanpa role Teradocu sendiri tetap diaudit supaya
+// grant SharePoint di sana terlapor sebagai extra. Lookup folder dan baca permission lewat /$batch.
+func auditPermissions(db *sql.DB, pathFilter string) error {
+	rows, err := database.GetFolderList(db, pathFilter)
+	if err != nil {
+		return err
+	}
+
+	type folder struct {
+		id, path string
+		desired  map[string]*expectedGrant
+	}
+	var list []folder
+	for rows.Next() {
+		var id, path string
+		var parentID sql.NullString
+		if err := rows.Scan(&id, &parentID, &path); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+		list = append(list, folder{id: id, path: path})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var drifts []permissionDrift
+	audited, failed := 0, 0
+
+	// 1. grant Teradocu semua folder, lalu email penerima di-resolve ke object id
+	expected, err := loadExpectedGrants(db, pathFilter)
+	if err != nil {
+		return fmt.Errorf("gagal membaca akses Teradocu: %w", err)
+	}
+	emailSet := make(map[string]bool)
+	for i := range list {
+		list[i].desired = expected[list[i].id]
+		for email := range list[i].desired {
+			if _, ok := siteGroupName(email); !ok {
+				emailSet[email] = true
+			}
+		}
+	}
+
+	resolver, err := sharepoint.NewDefaultResolver()
+	if err != nil {
+		return err
+	}
+	emails := make([]string, 0, len(emailSet))
+	for e := range emailSet {
+		emails = append(emails, e)
+	}
+	sort.Strings(emails)
+	users, missing, err := resolver.ResolveAll(emails)
+	if saveErr := resolver.Save(); saveErr != nil {
+		log.Printf("⚠️ Gagal menyimpan cache user: %v\n", saveErr)
+	}
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		log.Printf("⚠️ %d email tidak ditemukan di tenant, dicocokkan lewat email saja\n", len(missing))
+	}
+
+	// 2. item id dan permission semua folder lewat /$batch
+	lookups := make([]sharepoint.BatchRequest, 0, len(list))
+	for i, f := range list {
+		lookups = append(lookups, sharepoint.DriveItemRequest(strconv.Itoa(i), spPaths.MapFolder(f.path)))
+	}
+	found, err := sharepoint.ExecuteBatch(lookups)
+	if err != nil {
+		return fmt.Errorf("gagal lookup folder SharePoint: %w", err)
+	}
+
+	type folderItem struct {
+		folder
+		itemID string
+	}
+	var items []folderItem
+	for i, f := range list {
+		var item sharepoint.ItemResponse
+		if err := found[strconv.Itoa(i)].Decode(&item); err != nil {
+			log.Printf("❌ Gagal membaca permission SharePoint %s: %v\n", f.path, err)
+			failed++
+			continue
+		}
+		items = append(items, folderItem{folder: f, itemID: item.ID})
+	}
+
+	listReqs := make([]sharepoint.BatchRequest, 0, len(items))
+	for i, it := range items {
+		listReqs = append(listReqs, sharepoint.ListPermissionsRequest(strconv.Itoa(i), it.itemID))
+	}
+	listed, err := sharepoint.ExecuteBatch(listReqs)
+	if err != nil {
+		return fmt.Errorf("gagal membaca permission: %w", err)
+	}
+
+	// 3. bandingkan
+	for i, it := range items {
+		perms, err := sharepoint.DecodePermissions(listed[strconv.Itoa(i)])
+		if err != nil {
+			log.Printf("❌ Gagal membaca permission SharePoint %s: %v\n", it.path, err)
+			failed++
+			continue
+		}
+
+		audited++
+		drifts = append(drifts, diffFolderPermissions(it.id, it.path, it.desired, users, perms)...)
+	}
+
+	_ = os.MkdirAll("logs", os.ModePerm)
+	base := "logs/permission_audit_" + time.Now().Format("2006-01-02T15-04-05")
+
+	if err := writeDriftCSV(base+".csv", drifts); err != nil {
+		return err
+	}
+	if err := writeDriftJSON(base+".json", drifts); err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, d := range drifts {
+		counts[d.Kind]++
+	}
+	log.Printf("✅ Audit permission: %d folder diaudit, %d gagal | missing %d, extra %d, mismatch %d\n",
+		audited, failed, counts[driftMissing], counts[driftExtra], counts[driftMismatch])
+	log.Printf("📄 Laporan: %s.csv / %s.json\n", base, base)
+
+	if failed > 0 {
+		return fmt.Errorf("%d folder gagal diaudit, lihat log", failed)
+	}
+	return nil
+}
+
+func writeDriftCSV(path string, drifts []permissionDrift) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("gagal membuat %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"folder_id", "folder_path", "email", "kind", "teradocu_role", "sharepoint_role", "inherited"})
+	for _, d := range drifts {
+		w.Write([]string{d.FolderID, d.FolderPath, d.Email, d.Kind, d.TeradocuRole, d.SharepointRole, strconv.FormatBool(d.Inherited)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeDriftJSON(path string, drifts []permissionDrift) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("gagal membuat %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if drifts == nil {
+		drifts = []permissionDrift{}
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(drifts)
+}
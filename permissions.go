@@ -0,0 +1,589 @@
+package main
+
+import (
+	"bufio"
+	"converter_blob/database"
+	"converter_blob/logs"
+	"converter_blob/sharepoint"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// roleRank dipakai untuk memilih role tertinggi kalau satu email punya beberapa role di folder yang sama.
+var roleRank = map[string]int{
+	"read":  1,
+	"write": 2,
+	"owner": 3,
+}
+
+// folderAccess adalah hak akses yang seharusnya ada di satu folder menurut Teradocu.
+type folderAccess struct {
+	folderID string
+	parentID string
+	path     string
+	grants   map[string]string // penerima (email, profile_id atau siteGroupPrefix+nama) → role SharePoint
+}
+
+// sharepointRole menerjemahkan folder_role Teradocu ke role permission SharePoint dan site group
+// penerimanya (kosong kalau role diberikan per user). false berarti role dipetakan ke "none"
+// atau tidak dikenal.
+func sharepointRole(folderRole string) (string, string, bool) {
+	access, err := GetFolderRolePermission(folderRole)
+	if err != nil {
+		return "", "", false
+	}
+	return access.RolePermission, access.SharepointGroup, roleRank[access.RolePermission] > 0
+}
+
+func (fa *folderAccess) sameGrants(other *folderAccess) bool {
+	if len(fa.grants) != len(other.grants) {
+		return false
+	}
+	for email, role := range fa.grants {
+		if other.grants[email] != role {
+			return false
+		}
+	}
+	return true
+}
+
+func folderDepth(path string) int {
+	return strings.Count(strings.Trim(path, "/"), "/")
+}
+
+// addFolderGrant mencatat role SharePoint penerima di folder; kalau penerima punya beberapa
+// role di folder yang sama, role tertinggi yang dipakai. Role yang dipetakan ke sharepoint_group
+// dicatat untuk site group itu, bukan untuk principal.
+func addFolderGrant(folders map[string]*folderAccess, folderID, parentID, path, principal, folderRole string) {
+	role, group, ok := sharepointRole(folderRole)
+	if !ok {
+		log.Printf("⚠️ Role %s tidak punya padanan SharePoint, dilewati untuk %s\n", folderRole, principal)
+		return
+	}
+	if group != "" {
+		principal = siteGroupPrefix + group
+	}
+
+	fa, ok := folders[folderID]
+	if !ok {
+		fa = &folderAccess{
+			folderID: folderID,
+			parentID: parentID,
+			path:     path,
+			grants:   make(map[string]string),
+		}
+		folders[folderID] = fa
+	}
+
+	if roleRank[role] > roleRank[fa.grants[principal]] {
+		fa.grants[principal] = role
+	}
+}
+
+// loadFolderAccess membaca folder_profile_role dan menghitung role SharePoint per folder per email.
+func loadFolderAccess(db *sql.DB, pathFilter string) (map[string]*folderAccess, error) {
+	rows, err := database.GetFolderAccessAll(db, pathFilter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := make(map[string]*folderAccess)
+
+	for rows.Next() {
+		var (
+			email, profileID, folderID, path, folderRole string
+			parentID                                     sql.NullString
+		)
+		if err := rows.Scan(&email, &profileID, &folderID, &parentID, &path, &folderRole); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+
+		addFolderGrant(folders, folderID, parentID.String, path, strings.ToLower(strings.TrimSpace(email)), folderRole)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// sortedFolders mengurutkan folder dari yang paling dangkal supaya parent diproses lebih dulu.
+func sortedFolders(folders map[string]*folderAccess) []*folderAccess {
+	list := make([]*folderAccess, 0, len(folders))
+	for _, fa := range folders {
+		list = append(list, fa)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		di, dj := folderDepth(list[i].path), folderDepth(list[j].path)
+		if di != dj {
+			return di < dj
+		}
+		return list[i].path < list[j].path
+	})
+	return list
+}
+
+func permissionRank(p sharepoint.Permission) int {
+	rank := 0
+	for _, r := range p.Roles {
+		if roleRank[r] > rank {
+			rank = roleRank[r]
+		}
+	}
+	return rank
+}
+
+// resolveGrantEmails memetakan semua email penerima grant ke user Entra ID. Email yang tidak ada
+// di tenant dicatat ke logs/unresolved_users_<waktu>.txt dan tidak diberi akses.
+func resolveGrantEmails(resolver *sharepoint.Resolver, folders map[string]*folderAccess) (map[string]sharepoint.ResolvedUser, error) {
+	emailSet := make(map[string]bool)
+	for _, fa := range folders {
+		for email := range fa.grants {
+			if _, ok := siteGroupName(email); !ok {
+				emailSet[email] = true
+			}
+		}
+	}
+	emails := make([]string, 0, len(emailSet))
+	for e := range emailSet {
+		emails = append(emails, e)
+	}
+	sort.Strings(emails)
+
+	users, missing, err := resolver.ResolveAll(emails)
+	if saveErr := resolver.Save(); saveErr != nil {
+		log.Printf("⚠️ Gagal menyimpan cache user: %v\n", saveErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		reportPath, err := writeUnresolvedReport(missing)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("⚠️ %d email tidak ditemukan di tenant dan dilewati (lihat %s)\n", len(missing), reportPath)
+	}
+
+	return users, nil
+}
+
+// writeUnresolvedReport menulis email yang tidak ada di tenant ke logs/unresolved_users_<waktu>.txt.
+func writeUnresolvedReport(missing []string) (string, error) {
+	if err := os.MkdirAll("logs", os.ModePerm); err != nil {
+		return "", err
+	}
+	reportPath := fmt.Sprintf("logs/unresolved_users_%s.txt", time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(reportPath, []byte(strings.Join(missing, "\n")+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return reportPath, nil
+}
+
+// folderItem adalah folder Teradocu yang sudah ditemukan di SharePoint beserta permission-nya.
+type folderItem struct {
+	*folderAccess
+	itemID  string
+	breakIt bool // role berbeda dari parent (atau parent tidak punya role sendiri)
+	broke   bool // inheritance diputus di run ini: role parent baru saja disalin
+	perms   []sharepoint.Permission
+}
+
+// resolveFolderItems mencari drive item semua folder (parent lebih dulu), memutus inheritance
+// folder yang role-nya berbeda dari parent, lalu membaca permission-nya. Lookup dan baca
+// permission lewat /$batch; putus inheritance lewat SharePoint REST (tidak bisa di-batch) dan
+// dicatat sebagai BREAK di logWriter. Folder yang gagal dilog, dihitung di failed dan tidak
+// ikut dikembalikan; broken adalah jumlah folder yang inheritance-nya diputus.
+func resolveFolderItems(folders map[string]*folderAccess, dryRun bool, logWriter *bufio.Writer) (items []folderItem, broken, failed int, err error) {
+
+	list := sortedFolders(folders)
+	lookups := make([]sharepoint.BatchRequest, 0, len(list))
+	for i, fa := range list {
+		lookups = append(lookups, sharepoint.DriveItemRequest(strconv.Itoa(i), spPaths.MapFolder(fa.path)))
+	}
+	found, err := sharepoint.ExecuteBatch(lookups)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("gagal lookup folder SharePoint: %w", err)
+	}
+
+	for i, fa := range list {
+		var item sharepoint.ItemResponse
+		if err := found[strconv.Itoa(i)].Decode(&item); err != nil {
+			log.Printf("❌ Folder tidak ditemukan di SharePoint: %s (%v)\n", fa.path, err)
+			failed++
+			continue
+		}
+
+		parent := folders[fa.parentID]
+		breakIt := parent == nil || !fa.sameGrants(parent)
+
+		var broke bool
+		if breakIt {
+			if dryRun {
+				log.Printf("🔎 [dry-run] Putus inheritance: %s\n", fa.path)
+			} else {
+				broke, err = sharepoint.BreakInheritance(item.ID)
+				if err != nil {
+					log.Printf("❌ Gagal putus inheritance %s: %v\n", fa.path, err)
+					failed++
+					continue
+				}
+				if broke {
+					broken++
+					logWriter.WriteString(fmt.Sprintf("[%s] BREAK %s\n", time.Now().Format(time.RFC3339), fa.path))
+				}
+			}
+		}
+		items = append(items, folderItem{folderAccess: fa, itemID: item.ID, breakIt: breakIt, broke: broke})
+	}
+
+	listReqs := make([]sharepoint.BatchRequest, 0, len(items))
+	for i, it := range items {
+		listReqs = append(listReqs, sharepoint.ListPermissionsRequest(strconv.Itoa(i), it.itemID))
+	}
+	listed, err := sharepoint.ExecuteBatch(listReqs)
+	if err != nil {
+		return nil, broken, failed, fmt.Errorf("gagal membaca permission: %w", err)
+	}
+
+	read := items[:0]
+	for i, it := range items {
+		perms, err := sharepoint.DecodePermissions(listed[strconv.Itoa(i)])
+		if err != nil {
+			log.Printf("❌ Gagal membaca permission %s: %v\n", it.path, err)
+			failed++
+			continue
+		}
+		it.perms = perms
+		read = append(read, it)
+	}
+
+	return read, broken, failed, nil
+}
+
+// siteGroupSync memberi SharePoint site group (sharepoint_group di role mapping) role-nya di
+// folder lewat SharePoint REST. Site group dicari sekali per nama dan harus sudah ada.
+type siteGroupSync struct {
+	ids       map[string]int // nama → id site group, 0 kalau tidak ada
+	mapped    map[string]bool
+	dryRun    bool
+	logWriter *bufio.Writer
+}
+
+func newSiteGroupSync(dryRun bool, logWriter *bufio.Writer) *siteGroupSync {
+	return &siteGroupSync{ids: make(map[string]int), mapped: mappedSiteGroups(), dryRun: dryRun, logWriter: logWriter}
+}
+
+func (s *siteGroupSync) groupID(name string) (int, error) {
+	if id, ok := s.ids[name]; ok {
+		return id, nil
+	}
+	g, err := sharepoint.FindSiteGroup(name)
+	if err != nil {
+		return 0, err
+	}
+	if g != nil {
+		s.ids[name] = g.ID
+	} else {
+		s.ids[name] = 0
+	}
+	return s.ids[name], nil
+}
+
+// apply menyamakan role site group target role mapping di satu folder. Folder yang masih
+// mewarisi parent sudah mendapat role yang sama dari sana (addroleassignment juga ditolak di
+// item seperti itu); di folder yang inheritance-nya diputus di run ini, salinan role site group
+// target yang tidak diharapkan dicabut, site group lain dibiarkan.
+func (s *siteGroupSync) apply(fa folderItem) (granted, pruned, failed int) {
+	if !fa.breakIt {
+		return 0, 0, 0
+	}
+
+	direct := make(map[string]sharepoint.Permission) // nama site group → permission langsung
+	for _, p := range fa.perms {
+		if name := p.GroupName(); name != "" && !p.Inherited() && s.mapped[name] {
+			direct[name] = p
+		}
+	}
+
+	want := make(map[string]string) // nama site group → role
+	for principal, role := range fa.grants {
+		if name, ok := siteGroupName(principal); ok {
+			want[name] = role
+		}
+	}
+
+	if fa.broke {
+		for name, p := range direct {
+			if _, ok := want[name]; ok {
+				continue
+			}
+			id, err := strconv.Atoi(p.GroupID())
+			if err == nil {
+				err = sharepoint.RemoveSiteGroupItemRole(fa.itemID, id)
+			}
+			if err != nil {
+				log.Printf("❌ Gagal cabut grant salinan %s di %s: %v\n", name, fa.path, err)
+				failed++
+				continue
+			}
+			pruned++
+			s.logWriter.WriteString(fmt.Sprintf("[%s] REMOVE %s site group %s\n", time.Now().Format(time.RFC3339), fa.path, name))
+		}
+	}
+
+	names := make([]string, 0, len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		role := want[name]
+		if p, ok := direct[name]; ok && permissionRank(p) == roleRank[role] {
+			continue
+		}
+		if s.dryRun {
+			log.Printf("🔎 [dry-run] Share %s (%s) ke site group %s\n", fa.path, role, name)
+			continue
+		}
+		id, err := s.groupID(name)
+		if err == nil && id == 0 {
+			err = fmt.Errorf("site group %s tidak ada di site", name)
+		}
+		if err == nil {
+			err = sharepoint.SetSiteGroupItemRole(fa.itemID, id, role)
+		}
+		if err != nil {
+			log.Printf("❌ Gagal share folder %s (%s) ke site group %s: %v\n", fa.path, role, name, err)
+			s.logWriter.WriteString(fmt.Sprintf("[%s] ERROR %s: %v\n", time.Now().Format(time.RFC3339), fa.path, err))
+			failed++
+			continue
+		}
+		granted++
+		log.Println("📂 Berhasil share folder:", fa.path, "ke site group", name, "sebagai", role)
+		s.logWriter.WriteString(fmt.Sprintf("[%s] SHARED %s (%s) to site group %s\n", time.Now().Format(time.RFC3339), fa.path, role, name))
+	}
+
+	return granted, pruned, failed
+}
+
+// syncPermissions menerapkan hak akses Teradocu ke folder SharePoint secara idempotent:
+// hanya grant yang belum ada/berbeda yang dikirim, dan inheritance diputus di folder yang
+// role-nya berbeda dari parent (atau parent tidak punya role sendiri). Role parent disalin saat
+// inheritance diputus supaya Owners/Members site tetap punya akses; grant user salinan yang
+// tidak ada di Teradocu dicabut dari folder yang diputus di run ini. Role yang dipetakan ke
+// sharepoint_group diberikan ke site group itu (siteGroupSync). Lookup folder, baca permission
+// dan perubahan role/grant user dikirim lewat /$batch.
+func syncPermissions(db *sql.DB, pathFilter string, dryRun bool) error {
+	folders, err := loadFolderAccess(db, pathFilter)
+	if err != nil {
+		return fmt.Errorf("gagal membaca akses folder: %w", err)
+	}
+
+	resolver, err := sharepoint.NewDefaultResolver()
+	if err != nil {
+		return err
+	}
+	users, err := resolveGrantEmails(resolver, folders)
+	if err != nil {
+		return err
+	}
+
+	logWriter := logs.SetLog("sharepoint_log.txt")
+	defer logs.LogFlush(logWriter)
+
+	var granted, updated, pruned int
+	startTime := time.Now()
+
+	// 1-3. item id, putus inheritance dan permission semua folder
+	items, broken, failed, err := resolveFolderItems(folders, dryRun, logWriter)
+	if err != nil {
+		return err
+	}
+	siteGroups := newSiteGroupSync(dryRun, logWriter)
+
+	// 4. hitung perubahan per folder; ops dikirim sekaligus di langkah 5
+	type permissionOp struct {
+		req    sharepoint.BatchRequest
+		path   string
+		role   string
+		emails []string
+		update bool
+		remove bool
+	}
+	var ops []permissionOp
+
+	for _, fa := range items {
+		perms := fa.perms
+
+		// role yang dipetakan ke sharepoint_group lewat SharePoint REST, di luar /$batch
+		g, p, f := siteGroups.apply(fa)
+		granted, pruned, failed = granted+g, pruned+p, failed+f
+
+		// permission dicocokkan lewat object id user, atau email untuk undangan yang belum diterima
+		direct := make(map[string]sharepoint.Permission)
+		inheritedRank := make(map[string]int)
+		for _, p := range perms {
+			for _, key := range []string{p.UserID(), p.Email()} {
+				if key == "" {
+					continue
+				}
+				if p.Inherited() {
+					if r := permissionRank(p); r > inheritedRank[key] {
+						inheritedRank[key] = r
+					}
+					continue
+				}
+				direct[key] = p
+			}
+		}
+
+		invites := make(map[string][]string) // role → email
+		for email, role := range fa.grants {
+			user, ok := users[email]
+			if !ok {
+				continue
+			}
+			key := user.ID
+			if _, ok := direct[key]; !ok {
+				key = email
+			}
+			if p, ok := direct[key]; ok {
+				if permissionRank(p) == roleRank[role] {
+					continue
+				}
+				if dryRun {
+					log.Printf("🔎 [dry-run] Ubah role %s di %s: %v → %s\n", email, fa.path, p.Roles, role)
+					continue
+				}
+				ops = append(ops, permissionOp{
+					req:    sharepoint.UpdatePermissionRolesRequest(strconv.Itoa(len(ops)), fa.itemID, p.ID, []string{role}),
+					path:   fa.path,
+					role:   role,
+					emails: []string{email},
+					update: true,
+				})
+				continue
+			}
+			if !fa.breakIt && max(inheritedRank[user.ID], inheritedRank[email]) >= roleRank[role] {
+				continue
+			}
+			invites[role] = append(invites[role], email)
+		}
+
+		// grant user yang ikut tersalin dari parent saat inheritance diputus; group (Owners/Members
+		// site, group profile) dibiarkan
+		if fa.broke {
+			expected := make(map[string]bool)
+			for email := range fa.grants {
+				expected[email] = true
+				if user, ok := users[email]; ok {
+					expected[user.ID] = true
+				}
+			}
+			for _, p := range perms {
+				if p.Inherited() || p.GroupID() != "" {
+					continue
+				}
+				who := p.Email()
+				if who == "" {
+					who = p.UserID()
+				}
+				if who == "" || expected[p.UserID()] || expected[p.Email()] {
+					continue
+				}
+				ops = append(ops, permissionOp{
+					req:    sharepoint.DeletePermissionRequest(strconv.Itoa(len(ops)), fa.itemID, p.ID),
+					path:   fa.path,
+					role:   strings.Join(p.Roles, ","),
+					emails: []string{who},
+					remove: true,
+				})
+			}
+		}
+
+		roles := make([]string, 0, len(invites))
+		for role := range invites {
+			roles = append(roles, role)
+		}
+		sort.Strings(roles)
+		for _, role := range roles {
+			emails := invites[role]
+			sort.Strings(emails)
+			if dryRun {
+				log.Printf("🔎 [dry-run] Share %s (%s) ke %v\n", fa.path, role, emails)
+				continue
+			}
+			// invite dibatasi jumlah penerima per request
+			for i := 0; i < len(emails); i += 20 {
+				batch := emails[i:min(i+20, len(emails))]
+				ids := make([]string, 0, len(batch))
+				for _, email := range batch {
+					ids = append(ids, users[email].ID)
+				}
+				ops = append(ops, permissionOp{
+					req:    sharepoint.InviteObjectsRequest(strconv.Itoa(len(ops)), fa.itemID, ids, role),
+					path:   fa.path,
+					role:   role,
+					emails: batch,
+				})
+			}
+		}
+	}
+
+	// 5. kirim semua perubahan lewat /$batch
+	if len(ops) > 0 {
+		reqs := make([]sharepoint.BatchRequest, 0, len(ops))
+		for _, op := range ops {
+			reqs = append(reqs, op.req)
+		}
+		results, err := sharepoint.ExecuteBatch(reqs)
+		if err != nil {
+			return fmt.Errorf("gagal mengirim perubahan permission: %w", err)
+		}
+
+		for _, op := range ops {
+			res := results[op.req.ID]
+			switch {
+			case op.remove && res.Err != nil:
+				log.Printf("❌ Gagal cabut grant salinan %s di %s: %v\n", op.emails[0], op.path, res.Err)
+				failed++
+			case op.remove:
+				pruned++
+				logWriter.WriteString(fmt.Sprintf("[%s] REMOVE %s %s %s\n", time.Now().Format(time.RFC3339), op.path, op.emails[0], op.role))
+			case op.update && res.Err != nil:
+				log.Printf("❌ Gagal ubah role %s di %s: %v\n", op.emails[0], op.path, res.Err)
+				failed++
+			case op.update:
+				updated++
+				logWriter.WriteString(fmt.Sprintf("[%s] UPDATE %s %s %s\n", time.Now().Format(time.RFC3339), op.path, op.emails[0], op.role))
+			case res.Err != nil:
+				log.Printf("❌ Gagal share folder %s (%s) ke %v: %v\n", op.path, op.role, op.emails, res.Err)
+				logWriter.WriteString(fmt.Sprintf("[%s] ERROR %s: %v\n", time.Now().Format(time.RFC3339), op.path, res.Err))
+				failed++
+			default:
+				granted += len(op.emails)
+				log.Println("📂 Berhasil share folder:", op.path, "ke", op.emails, "sebagai", op.role)
+				logWriter.WriteString(fmt.Sprintf("[%s] SHARED %s (%s) to %v\n", time.Now().Format(time.RFC3339), op.path, op.role, op.emails))
+			}
+		}
+	}
+
+	log.Printf("✅ Sinkronisasi permission selesai: %d folder, %d grant baru, %d role diubah, %d grant salinan dicabut, %d inheritance diputus, %d gagal (%s)\n",
+		len(folders), granted, updated, pruned, broken, failed, time.Since(startTime))
+
+	if failed > 0 {
+		return fmt.Errorf("%d operasi gagal, lihat log", failed)
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"converter_blob/manifest"
+	"converter_blob/sharepoint"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+const uploadWorkers = 5
+
+// diskBudget membatasi total byte file hasil ekstraksi yang masih ada di disk.
+// Worker ekstraksi memblok di acquire sampai upload (dengan --delete-after-upload) membebaskan ruang.
+// Nil berarti tanpa batas.
+type diskBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int64
+	used  int64
+	stuck int64 // bagian used milik file yang tetap di disk (upload gagal/tidak terverifikasi)
+}
+
+// checkDiskBudgetFlags menolak --max-disk-mb di mode --pipeline tanpa --delete-after-upload:
+// file yang disimpan tidak pernah membebaskan ruang, jadi batas disk tidak bisa dipegang.
+func checkDiskBudgetFlags(pipeline, deleteAfterUpload bool, maxDiskMB int64) error {
+	if pipeline && maxDiskMB > 0 && !deleteAfterUpload {
+		return fmt.Errorf("--max-disk-mb di mode --pipeline wajib dipakai bersama --delete-after-upload")
+	}
+	return nil
+}
+
+func newDiskBudget(limitMB int64) *diskBudget {
+	if limitMB <= 0 {
+		return nil
+	}
+	b := &diskBudget{limit: limitMB * 1024 * 1024}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *diskBudget) acquire(n int64) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// file yang lebih besar dari batas tetap boleh jalan kalau tidak ada file lain di disk
+	for b.used > 0 && b.used+n > b.limit {
+		if b.used == b.stuck {
+			// tidak ada upload yang akan membebaskan ruang; menunggu berarti deadlock
+			return fmt.Errorf("disk budget penuh oleh %.2f MB file yang gagal di-upload", float64(b.stuck)/(1024*1024))
+		}
+		b.cond.Wait()
+	}
+	b.used += n
+	return nil
+}
+
+func (b *diskBudget) release(n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// hold menandai n byte sebagai milik file yang tetap di disk sampai run selesai.
+func (b *diskBudget) hold(n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.stuck += n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// uploader meng-upload file hasil ekstraksi ke SharePoint dan mencatat hasilnya ke manifest.
+// Unit kerjanya adalah grup: semua versi satu dokumen yang harus di-upload berurutan.
+type uploader struct {
+	store             *manifest.Store
+	budget            *diskBudget
+	deleteAfterUpload bool
+	fields            *fieldUpdater
+	conflict          sharepoint.ConflictPolicy
+
+	count   int32
+	skipped int32
+	mu      sync.Mutex
+	failed  [][]extracted
+	already []string
+}
+
+func (u *uploader) upload(f extracted) error {
+	item, err := sharepoint.UploadFile(f.localPath, f.sharePointPath, sharepoint.UploadOptions{
+		ModifiedAt: f.modifiedAt,
+		CreatedAt:  f.createdAt,
+		Conflict:   u.conflict,
+	})
+	if err == nil && item.Skipped != "" {
+		atomic.AddInt32(&u.skipped, 1)
+		recordSkippedUpload(u.store, f, item)
+		log.Printf("⏭️ Dilewati (%s): %s", item.Skipped, f.sharePointPath)
+		if u.deleteAfterUpload && !f.isDummy {
+			// skip/newer tidak menjamin isi SharePoint sama dengan file lokal
+			if err := verifySkipped(f, item); err != nil {
+				log.Printf("⚠️ File lokal tidak dihapus: %v", err)
+				return nil
+			}
+		}
+		u.removeLocal(f)
+		return nil
+	}
+	if err == nil && u.deleteAfterUpload && !f.isDummy {
+		err = verifyUpload(f)
+	}
+	if err != nil {
+		if item != nil {
+			recordUploadFailed(u.store, f.documentID, f.version, item, err)
+		} else {
+			recordFailed(u.store, f.documentID, f.version, err)
+		}
+		return err
+	}
+
+	atomic.AddInt32(&u.count, 1)
+	recordUploaded(u.store, f, item)
+
+	u.fields.queue(f, item.ID)
+
+	log.Printf("✔️ Uploaded: %s (%.2f MB)", filepath.Base(f.localPath), f.sizeMB)
+	u.removeLocal(f)
+	return nil
+}
+
+// removeLocal menghapus file lokal pada --delete-after-upload setelah file ada di SharePoint.
+func (u *uploader) removeLocal(f extracted) {
+	if u.deleteAfterUpload && !f.isDummy {
+		if err := os.Remove(f.localPath); err != nil {
+			log.Printf("⚠️ Gagal hapus file lokal %s: %v", f.localPath, err)
+		}
+	}
+}
+
+// uploadGroup meng-upload versi secara berurutan dan berhenti di versi pertama yang gagal,
+// supaya urutan versi di SharePoint tetap sama dengan Teradocu. Mengembalikan sisa yang belum ter-upload.
+func (u *uploader) uploadGroup(g []extracted) ([]extracted, error) {
+	for i, f := range g {
+		if err := u.upload(f); err != nil {
+			for _, rest := range g[i+1:] {
+				recordFailed(u.store, rest.documentID, rest.version, fmt.Errorf("versi sebelumnya gagal di-upload"))
+			}
+			return g[i:], err
+		}
+	}
+	return nil, nil
+}
+
+// verifySkipped memastikan item SharePoint yang dipertahankan (--sp-conflict skip/newer) sama
+// dengan file lokal: ukuran harus sama, dan QuickXorHash juga kalau SharePoint punya hash.
+func verifySkipped(f extracted, item *sharepoint.ItemResponse) error {
+	fi, err := os.Stat(f.localPath)
+	if err != nil {
+		return err
+	}
+	if item.Size != fi.Size() {
+		return fmt.Errorf("%s: ukuran SharePoint %d != lokal %d", f.sharePointPath, item.Size, fi.Size())
+	}
+	remote := item.QuickXorHash()
+	if remote == "" {
+		return nil
+	}
+	local := item.LocalQuickXorHash
+	if local == "" {
+		file, err := os.Open(f.localPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		h := sharepoint.NewQuickXorHash()
+		if _, err := io.Copy(h, file); err != nil {
+			return err
+		}
+		local = sharepoint.QuickXorHashString(h)
+	}
+	if local != remote {
+		return fmt.Errorf("%s: quickXorHash SharePoint %s != lokal %s", f.sharePointPath, remote, local)
+	}
+	return nil
+}
+
+// verifyUpload memastikan item di SharePoint berukuran sama dengan file lokal sebelum file dihapus.
+func verifyUpload(f extracted) error {
+	fi, err := os.Stat(f.localPath)
+	if err != nil {
+		return err
+	}
+	item, err := sharepoint.GetDriveItem(f.sharePointPath)
+	if err != nil {
+		return fmt.Errorf("verifikasi upload gagal: %w", err)
+	}
+	if item.Size != fi.Size() {
+		return fmt.Errorf("verifikasi upload gagal: ukuran SharePoint %d != lokal %d", item.Size, fi.Size())
+	}
+	return nil
+}
+
+// run menjalankan n worker upload yang membaca groups sampai channel ditutup.
+func (u *uploader) run(groups <-chan []extracted, n int, bar *progressbar.ProgressBar) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range groups {
+				u.uploadFirstPass(g)
+				for _, f := range g {
+					u.settleBudget(f)
+				}
+				bar.Add(len(g))
+			}
+		}()
+	}
+	return &wg
+}
+
+// settleBudget membebaskan disk budget f setelah pass pertama. Budget hanya ada bersama
+// --delete-after-upload (lihat checkDiskBudgetFlags), jadi budget baru dibebaskan kalau file
+// lokal sudah tidak ada; file yang gagal di-upload, gagal diverifikasi atau gagal dihapus tetap
+// memegang budget karena file-nya masih di disk sampai retry.
+func (u *uploader) settleBudget(f extracted) {
+	if f.reserved <= 0 {
+		return
+	}
+	if _, err := os.Stat(f.localPath); os.IsNotExist(err) {
+		u.budget.release(f.reserved)
+	} else {
+		u.budget.hold(f.reserved)
+	}
+}
+
+func (u *uploader) uploadFirstPass(g []extracted) {
+	rest, err := u.uploadGroup(g)
+	if err == nil {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if sharepoint.IsConflict(err) {
+		for _, f := range rest {
+			u.already = append(u.already, f.localPath)
+		}
+		log.Printf("❌ Upload gagal (409): %s", rest[0].localPath)
+	} else {
+		u.failed = append(u.failed, rest)
+		log.Printf("❌ Upload gagal: %s (%v)", rest[0].localPath, err)
+	}
+}
+
+// retryFailed mengulang upload grup yang gagal di pass pertama secara serial.
+func (u *uploader) retryFailed() []string {
+	var failedFinal []string
+	if len(u.failed) == 0 {
+		return nil
+	}
+
+	total := countFiles(u.failed)
+	log.Printf("\n🔄 Retry upload untuk %d file yang gagal...", total)
+	barRetry := progressbar.Default(int64(total), "Retrying")
+
+	for _, g := range u.failed {
+		rest, err := u.uploadGroup(g)
+		if err != nil {
+			for _, f := range rest {
+				failedFinal = append(failedFinal, f.localPath)
+			}
+			log.Printf("❌ Retry gagal: %s (%v)", rest[0].localPath, err)
+		} else {
+			log.Printf("✔️ Retry sukses: %s", filepath.Base(g[len(g)-1].localPath))
+		}
+		barRetry.Add(len(g))
+	}
+	barRetry.Finish()
+
+	if len(failedFinal) > 0 {
+		_ = os.WriteFile("upload_failed_final.txt", []byte(strings.Join(failedFinal, "\n")), 0644)
+		log.Printf("\n🚨 Masih ada %d file gagal setelah retry, cek upload_failed_final.txt", len(failedFinal))
+	} else {
+		log.Println("\n🎉 Semua file berhasil di-upload setelah retry!")
+	}
+
+	return failedFinal
+}
+
+func countFiles(groups [][]extracted) int {
+	n := 0
+	for _, g := range groups {
+		n += len(g)
+	}
+	return n
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"converter_blob/database"
+	"converter_blob/logs"
+	"converter_blob/sharepoint"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultProfileGroupPrefix = "TD-"
+
+// groupMember adalah anggota group di SharePoint/Entra ID. id dipakai untuk mencocokkan anggota
+// dengan user Teradocu (lihat groupBackend.memberID); ref dipakai untuk mengeluarkannya.
+type groupMember struct {
+	email string
+	id    string
+	ref   string
+}
+
+// groupBackend membungkus operasi group untuk satu jenis group (Entra ID atau SharePoint site group).
+type groupBackend interface {
+	find(name string) (string, error) // id group, "" kalau belum ada
+	create(name, description string) (string, error)
+	members(groupID string) ([]groupMember, error)
+	// memberID adalah id anggota untuk user Entra ID, sama dengan groupMember.id.
+	memberID(u sharepoint.ResolvedUser) string
+	addMember(groupID string, u sharepoint.ResolvedUser) error
+	removeMember(groupID string, m groupMember) error
+	// grant memberi group tepat satu role di drive item (baru atau mengganti role lama).
+	grant(itemID, groupID, role string, existing *sharepoint.Permission) error
+	// revoke mencabut permission langsung group di drive item.
+	revoke(itemID, groupID string, p sharepoint.Permission) error
+	// grantBreaksInheritance bernilai true kalau grant di item yang masih mewarisi permission
+	// otomatis memutus inheritance; false berarti inheritance harus diputus lebih dulu.
+	grantBreaksInheritance() bool
+}
+
+type entraGroups struct{}
+
+func (entraGroups) find(name string) (string, error) {
+	g, err := sharepoint.FindGroup(name)
+	if err != nil || g == nil {
+		return "", err
+	}
+	return g.ID, nil
+}
+
+func (entraGroups) create(name, description string) (string, error) {
+	g, err := sharepoint.CreateSecurityGroup(name, description)
+	if err != nil {
+		return "", err
+	}
+	return g.ID, nil
+}
+
+func (entraGroups) members(groupID string) ([]groupMember, error) {
+	users, err := sharepoint.ListGroupMembers(groupID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]groupMember, 0, len(users))
+	for _, u := range users {
+		list = append(list, groupMember{email: u.Email(), id: u.ID, ref: u.ID})
+	}
+	return list, nil
+}
+
+// Anggota group Entra ID dicocokkan lewat object id.
+func (entraGroups) memberID(u sharepoint.ResolvedUser) string { return u.ID }
+
+func (entraGroups) addMember(groupID string, u sharepoint.ResolvedUser) error {
+	return sharepoint.AddGroupMember(groupID, u.ID)
+}
+
+func (entraGroups) removeMember(groupID string, m groupMember) error {
+	return sharepoint.RemoveGroupMember(groupID, m.ref)
+}
+
+func (entraGroups) grant(itemID, groupID, role string, existing *sharepoint.Permission) error {
+	if existing != nil {
+		return sharepoint.UpdatePermissionRoles(itemID, existing.ID, []string{role})
+	}
+	return sharepoint.InviteGroupToItem(itemID, groupID, role)
+}
+
+// Graph invite di item yang masih mewarisi permission memutus inheritance sendiri.
+func (entraGroups) grantBreaksInheritance() bool { return true }
+
+func (entraGroups) revoke(itemID, groupID string, p sharepoint.Permission) error {
+	return sharepoint.DeletePermission(itemID, p.ID)
+}
+
+type siteGroups struct{}
+
+// membershipClaim adalah prefix login name SharePoint untuk user Entra ID.
+const membershipClaim = "i:0#.f|membership|"
+
+func (siteGroups) find(name string) (string, error) {
+	g, err := sharepoint.FindSiteGroup(name)
+	if err != nil || g == nil {
+		return "", err
+	}
+	return strconv.Itoa(g.ID), nil
+}
+
+func (siteGroups) create(name, description string) (string, error) {
+	g, err := sharepoint.CreateSiteGroup(name, description)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(g.ID), nil
+}
+
+func (siteGroups) members(groupID string) ([]groupMember, error) {
+	id, err := strconv.Atoi(groupID)
+	if err != nil {
+		return nil, err
+	}
+	users, err := sharepoint.ListSiteGroupUsers(id)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]groupMember, 0, len(users))
+	for _, u := range users {
+		// login name selain user Entra ID (akun sistem, group) tidak punya id dan dibiarkan
+		var upn string
+		if strings.HasPrefix(strings.ToLower(u.LoginName), membershipClaim) {
+			upn = strings.ToLower(u.LoginName[len(membershipClaim):])
+		}
+		list = append(list, groupMember{email: strings.ToLower(u.Email), id: upn, ref: strconv.Itoa(u.ID)})
+	}
+	return list, nil
+}
+
+// Site group tidak menyimpan object id; anggota dicocokkan lewat UPN di login name.
+func (siteGroups) memberID(u sharepoint.ResolvedUser) string {
+	return strings.ToLower(u.UserPrincipalName)
+}
+
+func (siteGroups) addMember(groupID string, u sharepoint.ResolvedUser) error {
+	id, err := strconv.Atoi(groupID)
+	if err != nil {
+		return err
+	}
+	return sharepoint.AddSiteGroupUser(id, u.UserPrincipalName)
+}
+
+func (siteGroups) removeMember(groupID string, m groupMember) error {
+	id, err := strconv.Atoi(groupID)
+	if err != nil {
+		return err
+	}
+	userID, err := strconv.Atoi(m.ref)
+	if err != nil {
+		return err
+	}
+	return sharepoint.RemoveSiteGroupUser(id, userID)
+}
+
+func (siteGroups) grant(itemID, groupID, role string, existing *sharepoint.Permission) error {
+	id, err := strconv.Atoi(groupID)
+	if err != nil {
+		return err
+	}
+	return sharepoint.SetSiteGroupItemRole(itemID, id, role)
+}
+
+// addroleassignment SharePoint REST ditolak selama item masih mewarisi permission.
+func (siteGroups) grantBreaksInheritance() bool { return false }
+
+func (siteGroups) revoke(itemID, groupID string, p sharepoint.Permission) error {
+	id, err := strconv.Atoi(groupID)
+	if err != nil {
+		return err
+	}
+	return sharepoint.RemoveSiteGroupItemRole(itemID, id)
+}
+
+func newGroupBackend(kind string) (groupBackend, error) {
+	switch kind {
+	case "entra":
+		return entraGroups{}, nil
+	case "site":
+		return siteGroups{}, nil
+	}
+	return nil, fmt.Errorf("group type %q tidak dikenal (entra atau site)", kind)
+}
+
+// profileGroupName membentuk nama group untuk profile Teradocu (prefix dari PROFILE_GROUP_PREFIX).
+func profileGroupName(profileID string) string {
+	prefix, ok := os.LookupEnv("PROFILE_GROUP_PREFIX")
+	if !ok {
+		prefix = defaultProfileGroupPrefix
+	}
+	return prefix + profileID
+}
+
+// loadProfileFolderAccess menghitung role SharePoint per folder per profile_id.
+func loadProfileFolderAccess(db *sql.DB, pathFilter string) (map[string]*folderAccess, error) {
+	rows, err := database.GetProfileFolderAccess(db, pathFilter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := make(map[string]*folderAccess)
+
+	for rows.Next() {
+		var (
+			profileID, folderID, path, folderRole string
+			parentID                              sql.NullString
+		)
+		if err := rows.Scan(&profileID, &folderID, &parentID, &path, &folderRole); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+
+		addFolderGrant(folders, folderID, parentID.String, path, profileID, folderRole)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// loadProfileMembers mengambil email user aktif per profile.
+func loadProfileMembers(db *sql.DB, profileIDs []string) (map[string]map[string]bool, error) {
+	rows, err := database.GetProfileMembers(db, profileIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make(map[string]map[string]bool)
+	for rows.Next() {
+		var profileID, email string
+		if err := rows.Scan(&profileID, &email); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+		if members[profileID] == nil {
+			members[profileID] = make(map[string]bool)
+		}
+		members[profileID][strings.ToLower(strings.TrimSpace(email))] = true
+	}
+
+	return members, rows.Err()
+}
+
+// syncProfileGroups mengelola akses SharePoint per profile Teradocu: satu group per profile_id
+// (dibuat kalau belum ada), anggota group disamakan dengan teradocu.user_profile (dicocokkan
+// lewat id Entra ID), lalu folder di-share ke group, bukan ke tiap user.
+func syncProfileGroups(db *sql.DB, pathFilter, groupType string, dryRun bool) error {
+	resolver, err := sharepoint.NewDefaultResolver()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resolver.Save(); err != nil {
+			log.Printf("⚠️ Gagal menyimpan cache user: %v\n", err)
+		}
+		if missing := resolver.Missing(); len(missing) > 0 {
+			log.Printf("⚠️ %d email tidak ditemukan di tenant: %v\n", len(missing), missing)
+		}
+	}()
+
+	backend, err := newGroupBackend(groupType)
+	if err != nil {
+		return err
+	}
+
+	folders, err := loadProfileFolderAccess(db, pathFilter)
+	if err != nil {
+		return fmt.Errorf("gagal membaca akses folder: %w", err)
+	}
+
+	profileSet := make(map[string]bool)
+	for _, fa := range folders {
+		for profileID := range fa.grants {
+			if _, ok := siteGroupName(profileID); !ok {
+				profileSet[profileID] = true
+			}
+		}
+	}
+	profiles := make([]string, 0, len(profileSet))
+	for p := range profileSet {
+		profiles = append(profiles, p)
+	}
+	sort.Strings(profiles)
+
+	members, err := loadProfileMembers(db, profiles)
+	if err != nil {
+		return fmt.Errorf("gagal membaca anggota profile: %w", err)
+	}
+
+	// anggota dicocokkan lewat id Entra ID, bukan email, supaya mailbox yang berganti nama
+	// (file alias) tidak dikeluarkan lalu ditambahkan lagi
+	emailSet := make(map[string]bool)
+	for _, set := range members {
+		for email := range set {
+			emailSet[email] = true
+		}
+	}
+	emails := make([]string, 0, len(emailSet))
+	for email := range emailSet {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	users, _, err := resolver.ResolveAll(emails)
+	if err != nil {
+		return err
+	}
+
+	logWriter := logs.SetLog("sharepoint_log.txt")
+	defer logs.LogFlush(logWriter)
+
+	var created, added, removed, granted, pruned, broken, failed int
+	startTime := time.Now()
+
+	groupIDs := make(map[string]string) // profile_id → id group
+	for _, profileID := range profiles {
+		name := profileGroupName(profileID)
+		groupID, err := backend.find(name)
+		if err != nil {
+			log.Printf("❌ Gagal mencari group %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		if groupID == "" {
+			if dryRun {
+				log.Printf("🔎 [dry-run] Buat group %s dengan %d anggota\n", name, len(members[profileID]))
+				continue
+			}
+			groupID, err = backend.create(name, "Akses folder Teradocu untuk profile "+profileID)
+			if err != nil {
+				log.Printf("❌ Gagal membuat group %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			created++
+			log.Println("👥 Group dibuat:", name)
+			logWriter.WriteString(fmt.Sprintf("[%s] GROUP %s %s\n", time.Now().Format(time.RFC3339), name, groupID))
+		}
+		groupIDs[profileID] = groupID
+
+		current, err := backend.members(groupID)
+		if err != nil {
+			log.Printf("❌ Gagal membaca anggota group %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		desired := make(map[string]string) // id anggota → email Teradocu
+		for email := range members[profileID] {
+			if u, ok := users[email]; ok {
+				desired[backend.memberID(u)] = email
+			}
+		}
+
+		existing := make(map[string]bool)
+		for _, m := range current {
+			if m.id == "" {
+				continue
+			}
+			existing[m.id] = true
+			if _, ok := desired[m.id]; ok {
+				continue
+			}
+			if dryRun {
+				log.Printf("🔎 [dry-run] Keluarkan %s dari %s\n", m.email, name)
+				continue
+			}
+			if err := backend.removeMember(groupID, m); err != nil {
+				log.Printf("❌ Gagal mengeluarkan %s dari %s: %v\n", m.email, name, err)
+				failed++
+				continue
+			}
+			removed++
+			logWriter.WriteString(fmt.Sprintf("[%s] MEMBER-REMOVE %s %s\n", time.Now().Format(time.RFC3339), name, m.email))
+		}
+
+		var add []string
+		for id, email := range desired {
+			if !existing[id] {
+				add = append(add, email)
+			}
+		}
+		sort.Strings(add)
+		for _, email := range add {
+			if dryRun {
+				log.Printf("🔎 [dry-run] Tambah %s ke %s\n", email, name)
+				continue
+			}
+			if err := backend.addMember(groupID, users[email]); err != nil {
+				log.Printf("❌ Gagal menambah %s ke %s: %v\n", email, name, err)
+				failed++
+				continue
+			}
+			added++
+			logWriter.WriteString(fmt.Sprintf("[%s] MEMBER-ADD %s %s\n", time.Now().Format(time.RFC3339), name, email))
+		}
+	}
+
+	items, brokenFolders, failedFolders, err := resolveFolderItems(folders, dryRun, logWriter)
+	if err != nil {
+		return err
+	}
+	broken += brokenFolders
+	failed += failedFolders
+	siteGroups := newSiteGroupSync(dryRun, logWriter)
+
+	for _, fa := range items {
+		g, p, f := siteGroups.apply(fa)
+		granted, pruned, failed = granted+g, pruned+p, failed+f
+
+		direct := make(map[string]sharepoint.Permission)
+		inheritedRank := make(map[string]int)
+		for _, p := range fa.perms {
+			id := p.GroupID()
+			if id == "" {
+				continue
+			}
+			if p.Inherited() {
+				if r := permissionRank(p); r > inheritedRank[id] {
+					inheritedRank[id] = r
+				}
+				continue
+			}
+			direct[id] = p
+		}
+
+		// role parent ikut tersalin saat inheritance diputus; group profile yang tidak punya akses
+		// di folder ini dicabut, group lain (Owners/Members site) dibiarkan
+		if fa.broke {
+			expected := make(map[string]bool)
+			for profileID := range fa.grants {
+				expected[groupIDs[profileID]] = true
+			}
+			for profileID, groupID := range groupIDs {
+				p, ok := direct[groupID]
+				if !ok || expected[groupID] {
+					continue
+				}
+				if err := backend.revoke(fa.itemID, groupID, p); err != nil {
+					log.Printf("❌ Gagal cabut grant salinan %s di %s: %v\n", profileGroupName(profileID), fa.path, err)
+					failed++
+					continue
+				}
+				delete(direct, groupID)
+				pruned++
+				logWriter.WriteString(fmt.Sprintf("[%s] REMOVE %s group %s\n", time.Now().Format(time.RFC3339), fa.path, profileGroupName(profileID)))
+			}
+		}
+
+		profileIDs := make([]string, 0, len(fa.grants))
+		for profileID := range fa.grants {
+			if _, ok := siteGroupName(profileID); !ok {
+				profileIDs = append(profileIDs, profileID)
+			}
+		}
+		sort.Strings(profileIDs)
+
+		// inherits: item masih mewarisi permission parent (tidak perlu diputus menurut Teradocu)
+		inherits := !fa.breakIt
+		for _, profileID := range profileIDs {
+			role := fa.grants[profileID]
+			groupID, ok := groupIDs[profileID]
+			if !ok {
+				if dryRun {
+					log.Printf("🔎 [dry-run] Share %s (%s) ke %s\n", fa.path, role, profileGroupName(profileID))
+				}
+				continue
+			}
+
+			var existing *sharepoint.Permission
+			if p, ok := direct[groupID]; ok {
+				if permissionRank(p) == roleRank[role] {
+					continue
+				}
+				existing = &p
+			} else if inherits && inheritedRank[groupID] >= roleRank[role] {
+				continue
+			}
+
+			if dryRun {
+				log.Printf("🔎 [dry-run] Share %s (%s) ke %s\n", fa.path, role, profileGroupName(profileID))
+				continue
+			}
+
+			// role parent kurang: site group butuh item yang sudah putus inheritance (Graph invite
+			// memutusnya sendiri), jadi diputus dulu dengan menyalin role parent
+			if inherits && !backend.grantBreaksInheritance() {
+				broke, err := sharepoint.BreakInheritance(fa.itemID)
+				if err != nil {
+					log.Printf("❌ Gagal putus inheritance %s, %s tidak di-share: %v\n", fa.path, profileGroupName(profileID), err)
+					logWriter.WriteString(fmt.Sprintf("[%s] ERROR %s: %v\n", time.Now().Format(time.RFC3339), fa.path, err))
+					failed++
+					continue
+				}
+				inherits = false
+				if broke {
+					broken++
+					logWriter.WriteString(fmt.Sprintf("[%s] BREAK %s\n", time.Now().Format(time.RFC3339), fa.path))
+				}
+			}
+
+			if err := backend.grant(fa.itemID, groupID, role, existing); err != nil {
+				log.Printf("❌ Gagal share folder %s (%s) ke %s: %v\n", fa.path, role, profileGroupName(profileID), err)
+				logWriter.WriteString(fmt.Sprintf("[%s] ERROR %s: %v\n", time.Now().Format(time.RFC3339), fa.path, err))
+				failed++
+				continue
+			}
+			granted++
+			log.Println("📂 Berhasil share folder:", fa.path, "ke", profileGroupName(profileID), "sebagai", role)
+			logWriter.WriteString(fmt.Sprintf("[%s] SHARED %s (%s) to group %s\n", time.Now().Format(time.RFC3339), fa.path, role, profileGroupName(profileID)))
+		}
+	}
+
+	log.Printf("✅ Sinkronisasi group selesai: %d profile, %d group dibuat, %d anggota ditambah, %d dikeluarkan, %d grant, %d grant salinan dicabut, %d inheritance diputus, %d gagal (%s)\n",
+		len(profiles), created, added, removed, granted, pruned, broken, failed, time.Since(startTime))
+
+	if failed > 0 {
+		return fmt.Errorf("%d operasi gagal, lihat log", failed)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"converter_blob/database"
+	"converter_blob/logs"
+	"converter_blob/sharepoint"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inactiveUser adalah user Teradocu yang aksesnya harus dicabut beserta alasannya.
+type inactiveUser struct {
+	email  string
+	reason string
+}
+
+// loadInactiveUsers membaca email Teradocu yang tidak punya employee_user aktif.
+func loadInactiveUsers(db *sql.DB) (map[string]inactiveUser, error) {
+	rows, err := database.GetInactiveEmployees(db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make(map[string]inactiveUser)
+	for rows.Next() {
+		var email string
+		var mapped bool
+		if err := rows.Scan(&email, &mapped); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+		reason := "employee_user tidak aktif"
+		if !mapped {
+			reason = "tidak lagi terhubung ke employee_user"
+		}
+		users[email] = inactiveUser{email: email, reason: reason}
+	}
+
+	return users, rows.Err()
+}
+
+// loadKnownEmails melengkapi cache email (lowercase) → ada di teradocu.person.
+func loadKnownEmails(db *sql.DB, cache map[string]bool, emails []string) error {
+	var missing []string
+	for _, e := range emails {
+		if _, ok := cache[e]; !ok {
+			missing = append(missing, e)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	rows, err := database.GetEmployeeStatusByEmails(db, missing)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for _, e := range missing {
+		cache[e] = false
+	}
+	for rows.Next() {
+		var email string
+		var active, mapped bool
+		if err := rows.Scan(&email, &active, &mapped); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+		cache[email] = true
+	}
+
+	return rows.Err()
+}
+
+// revokeInactive mencabut permission SharePoint langsung milik user Teradocu yang sudah tidak
+// aktif atau tidak terhubung lagi. Email Teradocu di-resolve ke object id Entra ID (termasuk
+// file alias untuk mailbox yang berganti nama) dan permission dicocokkan lewat object id;
+// email hanya dipakai untuk user yang tidak ditemukan di tenant dan undangan yang belum
+// diterima. Permission user yang emailnya tidak dikenal Teradocu tidak dicabut tapi dilaporkan.
+// Setiap pencabutan (dan rencana pada dry-run) dicatat ke logs/revocation_<waktu>.log.
+// Lookup folder, baca permission dan pencabutan dikirim lewat /$batch.
+func revokeInactive(db *sql.DB, pathFilter string, dryRun bool) error {
+	rows, err := database.GetFolderList(db, pathFilter)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for rows.Next() {
+		var id, path string
+		var parentID sql.NullString
+		if err := rows.Scan(&id, &parentID, &path); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 1. user nonaktif → object id Entra ID
+	inactive, err := loadInactiveUsers(db)
+	if err != nil {
+		return fmt.Errorf("gagal membaca user nonaktif: %w", err)
+	}
+	emails := make([]string, 0, len(inactive))
+	for email := range inactive {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	resolver, err := sharepoint.NewDefaultResolver()
+	if err != nil {
+		return err
+	}
+	resolved, missing, err := resolver.ResolveAll(emails)
+	if saveErr := resolver.Save(); saveErr != nil {
+		log.Printf("⚠️ Gagal menyimpan cache user: %v\n", saveErr)
+	}
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		log.Printf("⚠️ %d user nonaktif tidak ditemukan di tenant, dicocokkan lewat email saja\n", len(missing))
+	}
+	byID := make(map[string]inactiveUser, len(resolved))
+	for email, u := range resolved {
+		byID[u.ID] = inactive[email]
+	}
+	unresolved := make(map[string]bool, len(missing))
+	for _, email := range missing {
+		unresolved[email] = true
+	}
+
+	_ = os.MkdirAll("logs", os.ModePerm)
+	auditLog := logs.SetLog("logs/revocation_" + time.Now().Format("2006-01-02T15-04-05") + ".log")
+	defer logs.LogFlush(auditLog)
+
+	mode := "REVOKE"
+	if dryRun {
+		mode = "DRY-RUN"
+	}
+
+	var revoked, unknown, failed int
+
+	// 2. item id dan permission semua folder lewat /$batch
+	lookups := make([]sharepoint.BatchRequest, 0, len(paths))
+	for i, path := range paths {
+		lookups = append(lookups, sharepoint.DriveItemRequest(strconv.Itoa(i), spPaths.MapFolder(path)))
+	}
+	found, err := sharepoint.ExecuteBatch(lookups)
+	if err != nil {
+		return fmt.Errorf("gagal lookup folder SharePoint: %w", err)
+	}
+
+	type folderItem struct {
+		path   string
+		itemID string
+	}
+	var items []folderItem
+	for i, path := range paths {
+		var item sharepoint.ItemResponse
+		if err := found[strconv.Itoa(i)].Decode(&item); err != nil {
+			log.Printf("❌ Folder tidak ditemukan di SharePoint: %s (%v)\n", path, err)
+			failed++
+			continue
+		}
+		items = append(items, folderItem{path: path, itemID: item.ID})
+	}
+
+	listReqs := make([]sharepoint.BatchRequest, 0, len(items))
+	for i, it := range items {
+		listReqs = append(listReqs, sharepoint.ListPermissionsRequest(strconv.Itoa(i), it.itemID))
+	}
+	listed, err := sharepoint.ExecuteBatch(listReqs)
+	if err != nil {
+		return fmt.Errorf("gagal membaca permission: %w", err)
+	}
+
+	// 3. cocokkan permission langsung dengan user nonaktif
+	type revokeOp struct {
+		req    sharepoint.BatchRequest
+		path   string
+		who    string
+		line   string
+		reason string
+	}
+	var ops []revokeOp
+	known := make(map[string]bool)
+
+	for i, it := range items {
+		perms, err := sharepoint.DecodePermissions(listed[strconv.Itoa(i)])
+		if err != nil {
+			log.Printf("❌ Gagal membaca permission %s: %v\n", it.path, err)
+			failed++
+			continue
+		}
+
+		// permission inherited hanya bisa dicabut di folder asalnya; group tidak dicabut per user
+		var others []sharepoint.Permission
+		var otherEmails []string
+		for _, p := range perms {
+			if p.Inherited() || p.GroupID() != "" || (p.UserID() == "" && p.Email() == "") {
+				continue
+			}
+			user, ok := byID[p.UserID()]
+			if email := strings.ToLower(p.Email()); !ok && (p.UserID() == "" || unresolved[email]) {
+				user, ok = inactive[email]
+			}
+			if !ok {
+				if p.Email() != "" {
+					others = append(others, p)
+					otherEmails = append(otherEmails, strings.ToLower(p.Email()))
+				}
+				continue
+			}
+
+			who := p.Email()
+			if who == "" {
+				who = user.email
+			}
+			line := fmt.Sprintf("[%s] %s %s %s teradocu=%s roles=%s perm=%s reason=%q\n",
+				time.Now().Format(time.RFC3339), mode, it.path, who, user.email, strings.Join(p.Roles, ","), p.ID, user.reason)
+			if dryRun {
+				log.Printf("🔎 [dry-run] Cabut %s dari %s (%s)\n", who, it.path, user.reason)
+				auditLog.WriteString(line)
+				continue
+			}
+			ops = append(ops, revokeOp{
+				req:    sharepoint.DeletePermissionRequest(strconv.Itoa(len(ops)), it.itemID, p.ID),
+				path:   it.path,
+				who:    who,
+				line:   line,
+				reason: user.reason,
+			})
+		}
+
+		// email yang tidak ada di teradocu.person (admin tenant, tamu, mailbox yang belum di-alias)
+		if err := loadKnownEmails(db, known, otherEmails); err != nil {
+			log.Printf("❌ Gagal membaca status user %s: %v\n", it.path, err)
+			failed++
+			continue
+		}
+		for _, p := range others {
+			if known[strings.ToLower(p.Email())] {
+				continue
+			}
+			unknown++
+			auditLog.WriteString(fmt.Sprintf("[%s] UNKNOWN %s %s roles=%s perm=%s\n",
+				time.Now().Format(time.RFC3339), it.path, p.Email(), strings.Join(p.Roles, ","), p.ID))
+		}
+	}
+
+	// 4. cabut lewat /$batch
+	if len(ops) > 0 {
+		reqs := make([]sharepoint.BatchRequest, 0, len(ops))
+		for _, op := range ops {
+			reqs = append(reqs, op.req)
+		}
+		results, err := sharepoint.ExecuteBatch(reqs)
+		if err != nil {
+			return fmt.Errorf("gagal mengirim pencabutan: %w", err)
+		}
+		for _, op := range ops {
+			if err := results[op.req.ID].Err; err != nil {
+				log.Printf("❌ Gagal mencabut %s dari %s: %v\n", op.who, op.path, err)
+				auditLog.WriteString(fmt.Sprintf("[%s] ERROR %s %s: %v\n", time.Now().Format(time.RFC3339), op.path, op.who, err))
+				failed++
+				continue
+			}
+			revoked++
+			log.Printf("🚫 Akses %s dicabut dari %s (%s)\n", op.who, op.path, op.reason)
+			auditLog.WriteString(op.line)
+		}
+	}
+
+	log.Printf("✅ Rekonsiliasi user nonaktif selesai: %d folder, %d dicabut, %d permission email tidak dikenal Teradocu, %d gagal (dry-run: %v)\n",
+		len(paths), revoked, unknown, failed, dryRun)
+	if unknown > 0 {
+		log.Println("⚠️ Permission email yang tidak dikenal Teradocu tidak dicabut; cek baris UNKNOWN di log revocation (atau tambahkan ke file alias)")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d operasi gagal, lihat log", failed)
+	}
+	return nil
+}
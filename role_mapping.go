@@ -0,0 +1,225 @@
+package main
+
+import (
+	"converter_blob/database"
+	"converter_blob/types"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const defaultRoleMappingFile = "data/role_mapping.json"
+
+// RoleTarget adalah padanan SharePoint untuk satu folder_role Teradocu.
+// SharepointRole "none" berarti role sengaja tidak diberi akses di SharePoint.
+// Tanpa SharepointGroup, role permission (read/write/owner) diberikan ke tiap user atau ke group
+// profile (--sync-groups). Dengan SharepointGroup (mis. "Site Members"), folder di-share ke site
+// group yang sudah ada itu dengan SharepointRole, bukan ke tiap user; anggota site group
+// dikelola di SharePoint.
+type RoleTarget struct {
+	SharepointRole  string `json:"sharepoint_role"`
+	SharepointGroup string `json:"sharepoint_group,omitempty"`
+}
+
+// siteGroupPrefix menandai penerima grant berupa SharePoint site group (sharepoint_group) di
+// folderAccess.grants supaya tidak tertukar dengan email atau profile_id.
+const siteGroupPrefix = "sharepoint_group:"
+
+// siteGroupName mengembalikan nama site group kalau principal adalah penerima sharepoint_group.
+func siteGroupName(principal string) (string, bool) {
+	return strings.CutPrefix(principal, siteGroupPrefix)
+}
+
+// mappedSiteGroups mengembalikan nama semua site group yang menjadi target role mapping.
+func mappedSiteGroups() map[string]bool {
+	groups := make(map[string]bool)
+	if roleMapping == nil {
+		return groups
+	}
+	for _, t := range roleMapping.Roles {
+		if t.SharepointGroup != "" {
+			groups[t.SharepointGroup] = true
+		}
+	}
+	return groups
+}
+
+// RoleMapping dibaca dari file JSON, mis:
+//
+//	{
+//	  "role_column": "id",
+//	  "roles": {
+//	    "FOLDER_VIEWER": {"sharepoint_role": "read"},
+//	    "FOLDER_CONTRIBUTOR": {"sharepoint_role": "write", "sharepoint_group": "Site Members"},
+//	    "FOLDER_ADMIN": {"sharepoint_role": "owner"}
+//	  }
+//	}
+//
+// role_column adalah kolom teradocu.folder_role_master yang berisi nama role.
+type RoleMapping struct {
+	RoleColumn string                `json:"role_column"`
+	Roles      map[string]RoleTarget `json:"roles"`
+}
+
+// roleMapping aktif; nil berarti belum dimuat dan GetFolderRolePermission akan menolak semua role.
+var roleMapping *RoleMapping
+
+func loadRoleMapping(path string) (*RoleMapping, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca role mapping %s: %w", path, err)
+	}
+
+	var m RoleMapping
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("gagal decode role mapping %s: %w", path, err)
+	}
+	if m.RoleColumn == "" {
+		m.RoleColumn = "id"
+	}
+	if len(m.Roles) == 0 {
+		return nil, fmt.Errorf("role mapping %s tidak berisi role", path)
+	}
+
+	// field yang tidak dikenal ditolak, bukan diabaikan diam-diam
+	var raw struct {
+		Roles map[string]map[string]json.RawMessage `json:"roles"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("gagal decode role mapping %s: %w", path, err)
+	}
+	for role, fields := range raw.Roles {
+		for key := range fields {
+			switch key {
+			case "sharepoint_role", "sharepoint_group":
+			default:
+				return nil, fmt.Errorf("role mapping %s: field %q untuk %s tidak dikenal", path, key, role)
+			}
+		}
+	}
+
+	for role, t := range m.Roles {
+		if t.SharepointRole != "none" && roleRank[t.SharepointRole] == 0 {
+			return nil, fmt.Errorf("role mapping %s: sharepoint_role %q untuk %s tidak valid (read, write, owner, none)", path, t.SharepointRole, role)
+		}
+		if t.SharepointGroup != "" && roleRank[t.SharepointRole] == 0 {
+			return nil, fmt.Errorf("role mapping %s: sharepoint_group %q untuk %s butuh sharepoint_role read, write atau owner", path, t.SharepointGroup, role)
+		}
+	}
+
+	return &m, nil
+}
+
+// folderRoleNames mengambil semua role Teradocu: baris folder_role_master ditambah role yang
+// benar-benar dipakai di folder_profile_role.
+func folderRoleNames(db *sql.DB, roleColumn string) ([]string, error) {
+	rows, err := database.GetFolderRoleMaster(db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	idx := -1
+	for i, c := range columns {
+		if c == roleColumn {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("kolom %q tidak ada di folder_role_master (kolom: %s)", roleColumn, strings.Join(columns, ", "))
+	}
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		if b, ok := values[idx].([]byte); ok {
+			names[string(b)] = true
+		} else if values[idx] != nil {
+			names[fmt.Sprint(values[idx])] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	used, err := database.GetFolderRolesInUse(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range used {
+		names[r] = true
+	}
+
+	list := make([]string, 0, len(names))
+	for n := range names {
+		list = append(list, n)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// initRoleMapping memuat role mapping (ROLE_MAPPING atau data/role_mapping.json) dan memastikan
+// setiap role Teradocu punya padanan. Role yang tidak terdaftar membuat proses berhenti.
+func initRoleMapping(db *sql.DB) error {
+	path := os.Getenv("ROLE_MAPPING")
+	if path == "" {
+		path = defaultRoleMappingFile
+	}
+
+	m, err := loadRoleMapping(path)
+	if err != nil {
+		return err
+	}
+
+	roles, err := folderRoleNames(db, m.RoleColumn)
+	if err != nil {
+		return fmt.Errorf("gagal membaca role Teradocu: %w", err)
+	}
+
+	var unknown []string
+	for _, r := range roles {
+		if _, ok := m.Roles[r]; !ok {
+			unknown = append(unknown, r)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("role Teradocu belum ada di %s: %s", path, strings.Join(unknown, ", "))
+	}
+
+	roleMapping = m
+	log.Printf("🔐 Role mapping: %s (%d role)\n", path, len(m.Roles))
+	return nil
+}
+
+// GetFolderRolePermission menerjemahkan folder_role Teradocu ke role SharePoint sesuai role mapping.
+func GetFolderRolePermission(role string) (*types.FolderRoleAccess, error) {
+	if roleMapping == nil {
+		return nil, fmt.Errorf("role mapping belum dimuat")
+	}
+
+	t, ok := roleMapping.Roles[role]
+	if !ok {
+		return nil, fmt.Errorf("role %s tidak ada di role mapping", role)
+	}
+
+	return &types.FolderRoleAccess{
+		FolderRole:      role,
+		RolePermission:  t.SharepointRole,
+		SharepointGroup: t.SharepointGroup,
+	}, nil
+}
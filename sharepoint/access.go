@@ -1,11 +1,9 @@
 package sharepoint
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
+	"strings"
 )
 
 type Permission struct {
@@ -17,54 +15,174 @@ type Permission struct {
 			Email       string `json:"email"`
 		} `json:"user"`
 	} `json:"grantedTo"`
+	GrantedToV2 struct {
+		User struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"displayName"`
+			Email       string `json:"email"`
+		} `json:"user"`
+		Group struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"displayName"`
+		} `json:"group"`
+		SiteGroup struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"displayName"`
+		} `json:"siteGroup"`
+	} `json:"grantedToV2"`
+	Invitation struct {
+		Email string `json:"email"`
+	} `json:"invitation"`
+	InheritedFrom *struct {
+		ID string `json:"id"`
+	} `json:"inheritedFrom,omitempty"`
 }
 
-func GetAccessListPermission(folderPath string) error {
+// Email mengembalikan email penerima permission (lowercase), kosong untuk link/group.
+func (p Permission) Email() string {
+	email := p.GrantedTo.User.Email
+	if email == "" {
+		email = p.GrantedToV2.User.Email
+	}
+	if email == "" {
+		email = p.Invitation.Email
+	}
+	return strings.ToLower(email)
+}
+
+// UserID mengembalikan object id Entra ID penerima permission, kosong untuk undangan/link/group.
+func (p Permission) UserID() string {
+	return p.GrantedToV2.User.ID
+}
 
-	accessToken := GetToken()
-	siteID := os.Getenv("MS_SITE_ID")
-	if accessToken == "" || siteID == "" {
-		return fmt.Errorf("❌ Token atau SiteID belum diset di environment variable")
+// GroupID mengembalikan id penerima permission kalau berupa group: object id untuk group
+// Entra ID, id numerik untuk SharePoint site group. Kosong untuk user/link.
+func (p Permission) GroupID() string {
+	if p.GrantedToV2.Group.ID != "" {
+		return p.GrantedToV2.Group.ID
 	}
+	return p.GrantedToV2.SiteGroup.ID
+}
 
-	item, err := GetItemIDFromPath(accessToken, siteID, folderPath)
-	if err != nil {
-		return err
+// GroupName mengembalikan nama tampilan group penerima permission, kosong untuk user/link.
+func (p Permission) GroupName() string {
+	if p.GrantedToV2.Group.DisplayName != "" {
+		return p.GrantedToV2.Group.DisplayName
 	}
-	itemID := item.ID
+	return p.GrantedToV2.SiteGroup.DisplayName
+}
 
-	fmt.Printf("📂 Mengambil daftar akses untuk item: %s (ID: %s)\n", item.Name, itemID)
+// Inherited bernilai true kalau permission berasal dari folder parent.
+func (p Permission) Inherited() bool {
+	return p.InheritedFrom != nil && p.InheritedFrom.ID != ""
+}
 
-	url := fmt.Sprintf("https://graph.microsoft.com/v1.0/sites/%s/drive/items/%s/permissions", siteID, itemID)
+type permissionList struct {
+	Value []Permission `json:"value"`
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
+func permissionsPath(itemID string) string {
+	return fmt.Sprintf("/drives/%s/items/%s/permissions", os.Getenv("MS_DRIVE_ID"), itemID)
+}
+
+// ListPermissions mengambil semua permission drive item di MS_DRIVE_ID.
+func ListPermissions(itemID string) ([]Permission, error) {
+	var result permissionList
+	if err := graphGet(permissionsPath(itemID), &result); err != nil {
+		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return result.Value, nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+// ListPermissionsRequest adalah ListPermissions sebagai sub-request batch; hasilnya dibaca dengan DecodePermissions.
+func ListPermissionsRequest(id, itemID string) BatchRequest {
+	return BatchRequest{ID: id, Method: "GET", URL: permissionsPath(itemID)}
+}
+
+// DecodePermissions membaca hasil ListPermissionsRequest.
+func DecodePermissions(r *BatchResponse) ([]Permission, error) {
+	var result permissionList
+	if err := r.Decode(&result); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return result.Value, nil
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		fmt.Printf("❌ Gagal request: %s\n%s\n", resp.Status, body)
-		return fmt.Errorf("Gagal request: %s\n%s\n", resp.Status, body)
+// InviteToItem memberi role (read/write) ke daftar email tanpa mengirim email undangan.
+func InviteToItem(itemID string, emails []string, role string) error {
+	recipients := make([]map[string]string, 0, len(emails))
+	for _, e := range emails {
+		recipients = append(recipients, map[string]string{"email": e})
 	}
 
-	var result struct {
-		Value []Permission `json:"value"`
+	body := map[string]interface{}{
+		"recipients":     recipients,
+		"roles":          []string{role},
+		"requireSignIn":  true,
+		"sendInvitation": false,
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
+
+	path := fmt.Sprintf("/drives/%s/items/%s/invite", os.Getenv("MS_DRIVE_ID"), itemID)
+	return graphSend("POST", path, body, nil)
+}
+
+// InviteObjectsToItem memberi role ke user/group berdasarkan object id Entra ID,
+// jadi tidak ada undangan tamu untuk email yang salah ketik.
+func InviteObjectsToItem(itemID string, objectIDs []string, role string) error {
+	r := InviteObjectsRequest("", itemID, objectIDs, role)
+	return graphSend(r.Method, r.URL, r.Body, nil)
+}
+
+// InviteObjectsRequest adalah InviteObjectsToItem sebagai sub-request batch.
+func InviteObjectsRequest(id, itemID string, objectIDs []string, role string) BatchRequest {
+	recipients := make([]map[string]string, 0, len(objectIDs))
+	for _, oid := range objectIDs {
+		recipients = append(recipients, map[string]string{"objectId": oid})
 	}
 
-	fmt.Println("📋 Daftar Akses:")
-	for _, p := range result.Value {
-		fmt.Printf("- %s (%s): %v\n", p.GrantedTo.User.DisplayName, p.GrantedTo.User.Email, p.Roles)
+	body := map[string]interface{}{
+		"recipients":     recipients,
+		"roles":          []string{role},
+		"requireSignIn":  true,
+		"sendInvitation": false,
 	}
-	return nil
+
+	path := fmt.Sprintf("/drives/%s/items/%s/invite", os.Getenv("MS_DRIVE_ID"), itemID)
+	return BatchRequest{ID: id, Method: "POST", URL: path, Body: body}
+}
+
+// UpdatePermissionRoles mengganti role permission yang sudah ada (bukan inherited).
+func UpdatePermissionRoles(itemID, permissionID string, roles []string) error {
+	r := UpdatePermissionRolesRequest("", itemID, permissionID, roles)
+	return graphSend(r.Method, r.URL, r.Body, nil)
+}
+
+// UpdatePermissionRolesRequest adalah UpdatePermissionRoles sebagai sub-request batch.
+func UpdatePermissionRolesRequest(id, itemID, permissionID string, roles []string) BatchRequest {
+	body := map[string]interface{}{"roles": roles}
+	path := fmt.Sprintf("/drives/%s/items/%s/permissions/%s", os.Getenv("MS_DRIVE_ID"), itemID, permissionID)
+	return BatchRequest{ID: id, Method: "PATCH", URL: path, Body: body}
+}
+
+// DeletePermission mencabut permission langsung (bukan inherited) dari drive item.
+func DeletePermission(itemID, permissionID string) error {
+	r := DeletePermissionRequest("", itemID, permissionID)
+	return graphSend(r.Method, r.URL, nil, nil)
+}
+
+// DeletePermissionRequest adalah DeletePermission sebagai sub-request batch.
+func DeletePermissionRequest(id, itemID, permissionID string) BatchRequest {
+	path := fmt.Sprintf("/drives/%s/items/%s/permissions/%s", os.Getenv("MS_DRIVE_ID"), itemID, permissionID)
+	return BatchRequest{ID: id, Method: "DELETE", URL: path}
+}
+
+// GetAccessListPermission mengambil daftar permission folder (termasuk yang inherited)
+// berdasarkan path SharePoint yang sama dengan path upload.
+func GetAccessListPermission(folderPath string) ([]Permission, error) {
+	item, err := GetDriveItem(folderPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ListPermissions(item.ID)
 }
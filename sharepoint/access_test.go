@@ -0,0 +1,113 @@
+package sharepoint_test
+
+import (
+	"converter_blob/sharepoint"
+	"testing"
+)
+
+func findPermission(perms []sharepoint.Permission, email string) *sharepoint.Permission {
+	for i := range perms {
+		if perms[i].Email() == email {
+			return &perms[i]
+		}
+	}
+	return nil
+}
+
+func TestListPermissions(t *testing.T) {
+	srv := newServer(t)
+	srv.AddPermission("Arsip", "pemilik@example.com", "owner", true)
+	srv.AddPermission("Arsip", "a@example.com", "read", false)
+
+	folder, err := sharepoint.GetDriveItem("Arsip")
+	if err != nil {
+		t.Fatalf("folder: %v", err)
+	}
+	perms, err := sharepoint.ListPermissions(folder.ID)
+	if err != nil {
+		t.Fatalf("list permission: %v", err)
+	}
+	if len(perms) != 2 {
+		t.Fatalf("%d permission, seharusnya 2", len(perms))
+	}
+	if p := findPermission(perms, "pemilik@example.com"); p == nil || !p.Inherited() {
+		t.Errorf("permission pemilik seharusnya inherited: %+v", p)
+	}
+	p := findPermission(perms, "a@example.com")
+	if p == nil || p.Inherited() || p.UserID() != "user-a@example.com" {
+		t.Fatalf("permission langsung a@example.com tidak ditemukan: %+v", p)
+	}
+	if len(p.Roles) != 1 || p.Roles[0] != "read" {
+		t.Errorf("role a@example.com %v, seharusnya [read]", p.Roles)
+	}
+
+	// lewat batch hasilnya harus sama
+	results, err := sharepoint.ExecuteBatch([]sharepoint.BatchRequest{sharepoint.ListPermissionsRequest("perm", folder.ID)})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	batched, err := sharepoint.DecodePermissions(results["perm"])
+	if err != nil {
+		t.Fatalf("decode permission: %v", err)
+	}
+	if len(batched) != len(perms) {
+		t.Errorf("batch mengembalikan %d permission, seharusnya %d", len(batched), len(perms))
+	}
+}
+
+func TestInviteAndUpdateRoles(t *testing.T) {
+	srv := newServer(t)
+	srv.PutFile("Arsip/dok.pdf", []byte("isi"))
+
+	folder, err := sharepoint.GetDriveItem("Arsip")
+	if err != nil {
+		t.Fatalf("folder: %v", err)
+	}
+	if err := sharepoint.InviteToItem(folder.ID, []string{"b@example.com"}, "read"); err != nil {
+		t.Fatalf("invite email: %v", err)
+	}
+	if err := sharepoint.InviteObjectsToItem(folder.ID, []string{"user-c@example.com"}, "write"); err != nil {
+		t.Fatalf("invite object id: %v", err)
+	}
+
+	perms, err := sharepoint.ListPermissions(folder.ID)
+	if err != nil {
+		t.Fatalf("list permission: %v", err)
+	}
+	b := findPermission(perms, "b@example.com")
+	if b == nil || len(b.Roles) != 1 || b.Roles[0] != "read" {
+		t.Fatalf("grant b@example.com tidak sesuai: %+v", b)
+	}
+	var c *sharepoint.Permission
+	for i := range perms {
+		if perms[i].UserID() == "user-c@example.com" {
+			c = &perms[i]
+		}
+	}
+	if c == nil || len(c.Roles) != 1 || c.Roles[0] != "write" {
+		t.Fatalf("grant user-c@example.com tidak sesuai: %+v", c)
+	}
+
+	// naikkan b ke write langsung, turunkan c ke read lewat batch
+	if err := sharepoint.UpdatePermissionRoles(folder.ID, b.ID, []string{"write"}); err != nil {
+		t.Fatalf("update role: %v", err)
+	}
+	results, err := sharepoint.ExecuteBatch([]sharepoint.BatchRequest{
+		sharepoint.UpdatePermissionRolesRequest("c", folder.ID, c.ID, []string{"read"}),
+	})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if err := results["c"].Err; err != nil {
+		t.Fatalf("update role lewat batch: %v", err)
+	}
+
+	stored, _ := srv.Item("Arsip")
+	roles := make(map[string]string)
+	for _, p := range stored.Permissions {
+		roles[p.ID] = p.Roles[0]
+	}
+	if roles[b.ID] != "write" || roles[c.ID] != "read" {
+		t.Errorf("role di server %v, seharusnya %s=write dan %s=read", roles, b.ID, c.ID)
+	}
+}
@@ -0,0 +1,315 @@
+package sharepoint
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ================= TOKEN PROVIDER =================
+
+const graphScope = "https://graph.microsoft.com/.default"
+
+// TokenProvider mengambil access token untuk satu scope beserta waktu expire-nya.
+// Default-nya client credentials dari environment; SetTokenProvider dipakai untuk
+// sumber token lain (mis. token statis untuk server Graph palsu).
+type TokenProvider interface {
+	Token(scope string) (token string, expiry time.Time, err error)
+}
+
+// TokenError adalah penolakan dari Azure AD saat meminta token.
+type TokenError struct {
+	StatusCode    int
+	Code          string `json:"error"`             // mis. invalid_client
+	Description   string `json:"error_description"` // pesan AADSTS lengkap
+	CorrelationID string `json:"correlation_id"`
+}
+
+func (e *TokenError) Error() string {
+	msg := fmt.Sprintf("gagal mengambil token (status %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ", " + e.Code
+	}
+	msg += ")"
+	if e.Description != "" {
+		msg += ": " + strings.SplitN(e.Description, "\r\n", 2)[0]
+	}
+	if e.CorrelationID != "" {
+		msg += " [correlation-id " + e.CorrelationID + "]"
+	}
+	return msg
+}
+
+type cachedToken struct {
+	token  string
+	expiry time.Time
+}
+
+var (
+	tokenCache = make(map[string]cachedToken)
+	tokenMu    sync.Mutex
+
+	provider     TokenProvider
+	providerErr  error
+	providerOnce sync.Once
+)
+
+// SetTokenProvider mengganti sumber token dan mengosongkan cache token.
+func SetTokenProvider(p TokenProvider) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	providerOnce.Do(func() {})
+	provider = p
+	providerErr = nil
+	tokenCache = make(map[string]cachedToken)
+}
+
+// defaultProvider memakai sertifikat kalau MS_CLIENT_CERT_PATH diset, selain itu MS_CLIENT_SECRET.
+func defaultProvider() (TokenProvider, error) {
+	tenantID := os.Getenv("MS_TENANT_ID")
+	clientID := os.Getenv("MS_CLIENT_ID")
+	if tenantID == "" || clientID == "" {
+		return nil, fmt.Errorf("❌ MS_TENANT_ID atau MS_CLIENT_ID belum diset")
+	}
+
+	if certPath := os.Getenv("MS_CLIENT_CERT_PATH"); certPath != "" {
+		return NewClientCertificateCredential(tenantID, clientID, certPath, os.Getenv("MS_CLIENT_KEY_PATH"))
+	}
+
+	secret := os.Getenv("MS_CLIENT_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("❌ MS_CLIENT_SECRET atau MS_CLIENT_CERT_PATH belum diset")
+	}
+	return &ClientSecretCredential{TenantID: tenantID, ClientID: clientID, Secret: secret}, nil
+}
+
+func GetToken() (string, error) {
+	return getTokenForScope(graphScope)
+}
+
+// getTokenForScope mengambil token untuk scope tertentu (Graph atau SharePoint REST),
+// di-cache per scope sampai mendekati expire.
+func getTokenForScope(scope string) (string, error) {
+
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	if t, ok := tokenCache[scope]; ok && time.Now().Before(t.expiry) {
+		return t.token, nil
+	}
+
+	providerOnce.Do(func() {
+		provider, providerErr = defaultProvider()
+	})
+	if providerErr != nil {
+		return "", providerErr
+	}
+
+	token, expiry, err := provider.Token(scope)
+	if err != nil {
+		return "", err
+	}
+
+	// refresh sebelum expire
+	tokenCache[scope] = cachedToken{
+		token:  token,
+		expiry: expiry.Add(-5 * time.Minute),
+	}
+
+	return token, nil
+}
+
+// ================= CLIENT CREDENTIALS =================
+
+func tokenEndpoint(tenantID string) string {
+	return loginBaseURL() + "/" + tenantID + "/oauth2/v2.0/token"
+}
+
+// requestToken mengirim form client credentials ke endpoint token AAD.
+func requestToken(tenantID string, form map[string]string) (string, time.Time, error) {
+	tokenResp := struct {
+		Token     string `json:"access_token"`
+		ExpiresIn int    `json:"expires_in"`
+	}{}
+
+	resp, err := client().R().
+		SetFormData(form).
+		Post(tokenEndpoint(tenantID))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("gagal mengambil token: %w", err)
+	}
+
+	if resp.IsError() {
+		te := &TokenError{StatusCode: resp.StatusCode()}
+		if json.Unmarshal(resp.Body(), te) != nil {
+			te.Description = strings.TrimSpace(resp.String())
+		}
+		return "", time.Time{}, te
+	}
+
+	if err := json.Unmarshal(resp.Body(), &tokenResp); err != nil {
+		return "", time.Time{}, fmt.Errorf("gagal decode token: %w", err)
+	}
+
+	return tokenResp.Token, time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second), nil
+}
+
+// ClientSecretCredential: client credentials dengan client secret.
+type ClientSecretCredential struct {
+	TenantID string
+	ClientID string
+	Secret   string
+}
+
+func (c *ClientSecretCredential) Token(scope string) (string, time.Time, error) {
+	return requestToken(c.TenantID, map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     c.ClientID,
+		"client_secret": c.Secret,
+		"scope":         scope,
+	})
+}
+
+// ClientCertificateCredential: client credentials dengan client assertion (JWT RS256)
+// yang ditandatangani private key sertifikat aplikasi. Dibutuhkan SharePoint REST app-only.
+type ClientCertificateCredential struct {
+	TenantID    string
+	ClientID    string
+	Certificate *x509.Certificate
+	Key         *rsa.PrivateKey
+}
+
+// NewClientCertificateCredential membaca sertifikat dan private key RSA dari file PEM.
+// keyPath boleh kosong kalau key ada di file yang sama dengan sertifikat.
+func NewClientCertificateCredential(tenantID, clientID, certPath, keyPath string) (*ClientCertificateCredential, error) {
+	if keyPath == "" {
+		keyPath = certPath
+	}
+
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca sertifikat %s: %w", certPath, err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca private key %s: %w", keyPath, err)
+	}
+
+	cred := &ClientCertificateCredential{TenantID: tenantID, ClientID: clientID}
+
+	for block, rest := pem.Decode(certPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			if cred.Certificate, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, fmt.Errorf("sertifikat %s tidak valid: %w", certPath, err)
+			}
+			break
+		}
+	}
+	if cred.Certificate == nil {
+		return nil, fmt.Errorf("tidak ada CERTIFICATE di %s", certPath)
+	}
+
+	for block, rest := pem.Decode(keyPEM); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			cred.Key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			var key interface{}
+			if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+				var ok bool
+				if cred.Key, ok = key.(*rsa.PrivateKey); !ok {
+					err = fmt.Errorf("private key bukan RSA")
+				}
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("private key %s tidak valid: %w", keyPath, err)
+		}
+		break
+	}
+	if cred.Key == nil {
+		return nil, fmt.Errorf("tidak ada private key RSA di %s", keyPath)
+	}
+
+	return cred, nil
+}
+
+// assertion membuat JWT client assertion sesuai format Microsoft identity platform.
+func (c *ClientCertificateCredential) assertion() (string, error) {
+	thumb := sha1.Sum(c.Certificate.Raw)
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := time.Now()
+
+	header := map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.RawURLEncoding.EncodeToString(thumb[:]),
+	}
+	claims := map[string]interface{}{
+		"aud": tokenEndpoint(c.TenantID),
+		"iss": c.ClientID,
+		"sub": c.ClientID,
+		"jti": hex.EncodeToString(jti),
+		"nbf": now.Add(-time.Minute).Unix(),
+		"iat": now.Unix(),
+		"exp": now.Add(10 * time.Minute).Unix(),
+	}
+
+	hb, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	cb, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (c *ClientCertificateCredential) Token(scope string) (string, time.Time, error) {
+	assertion, err := c.assertion()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("gagal membuat client assertion: %w", err)
+	}
+
+	return requestToken(c.TenantID, map[string]string{
+		"grant_type":            "client_credentials",
+		"client_id":             c.ClientID,
+		"client_assertion_type": "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+		"client_assertion":      assertion,
+		"scope":                 scope,
+	})
+}
+
+// StaticToken selalu mengembalikan token yang sama; untuk server Graph palsu atau token manual.
+type StaticToken string
+
+func (s StaticToken) Token(scope string) (string, time.Time, error) {
+	return string(s), time.Now().Add(time.Hour), nil
+}
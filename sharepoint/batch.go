@@ -0,0 +1,227 @@
+package sharepoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Graph JSON batching: beberapa request digabung ke satu POST /$batch (maksimal 20 per batch).
+// Sub-request yang di-throttle (429/503/504) dikirim ulang di batch berikutnya setelah
+// Retry-After; request dengan DependsOn ikut diulang kalau dependensinya diulang.
+
+const (
+	maxBatchSize     = 20
+	batchRetryCount  = 5
+	batchDefaultWait = 5 * time.Second
+)
+
+// BatchRequest adalah satu sub-request. URL relatif ke versi API, mis. "/drives/{id}/items/{id}".
+type BatchRequest struct {
+	ID        string
+	Method    string
+	URL       string
+	Body      interface{}
+	DependsOn []string // ID request lain yang harus sukses lebih dulu
+}
+
+// BatchResponse adalah hasil satu sub-request; Err berisi *GraphError kalau status >= 400.
+type BatchResponse struct {
+	ID     string
+	Status int
+	Body   json.RawMessage
+	Err    error
+}
+
+// Decode mengisi out dari body respons.
+func (r *BatchResponse) Decode(out interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if len(r.Body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Body, out)
+}
+
+type batchWireRequest struct {
+	ID        string            `json:"id"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      interface{}       `json:"body,omitempty"`
+	DependsOn []string          `json:"dependsOn,omitempty"`
+}
+
+type batchWireResponse struct {
+	ID      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
+
+// ExecuteBatch menjalankan reqs lewat /$batch dan mengembalikan hasil per ID. Request harus
+// terurut: dependensi muncul sebelum request yang bergantung padanya. Error hanya dikembalikan
+// kalau panggilan /$batch sendiri gagal; kegagalan sub-request ada di BatchResponse.Err.
+func ExecuteBatch(reqs []BatchRequest) (map[string]*BatchResponse, error) {
+	results := make(map[string]*BatchResponse, len(reqs))
+	pending := reqs
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		var wait time.Duration
+		for start := 0; start < len(pending); {
+			chunk := nextBatchChunk(pending[start:])
+			if err := sendBatch(chunk, results); err != nil {
+				return results, err
+			}
+			start += len(chunk)
+		}
+
+		// kumpulkan yang perlu diulang: throttled, atau gagal 424 karena dependensinya throttled
+		var retry []BatchRequest
+		retrying := make(map[string]bool)
+		for _, r := range pending {
+			res := results[r.ID]
+			again := retryableStatus(res.Status)
+			if res.Status == http.StatusFailedDependency {
+				for _, d := range r.DependsOn {
+					again = again || retrying[d]
+				}
+			}
+			if !again {
+				continue
+			}
+			if ge, ok := AsGraphError(res.Err); ok && ge.RetryAfter > wait {
+				wait = ge.RetryAfter
+			}
+			retrying[r.ID] = true
+			retry = append(retry, r)
+		}
+		if len(retry) == 0 || attempt+1 >= batchRetryCount {
+			break
+		}
+		if wait <= 0 {
+			wait = batchDefaultWait
+		}
+		log.Printf("⏳ Batch: %d sub-request di-throttle, ulang dalam %s\n", len(retry), wait)
+		time.Sleep(wait)
+		pending = retry
+	}
+
+	return results, nil
+}
+
+// nextBatchChunk mengambil maksimal 20 request dari awal reqs. Request yang dependensinya belum
+// ikut di chunk ini dipindah ke chunk berikutnya (dependsOn hanya berlaku di dalam satu batch).
+func nextBatchChunk(reqs []BatchRequest) []BatchRequest {
+	n := min(len(reqs), maxBatchSize)
+	inChunk := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		inChunk[reqs[i].ID] = true
+	}
+	// potong sebelum request pertama yang dependensinya ada di luar chunk dan belum selesai:
+	// ID yang tidak ada di reqs berarti sudah dijalankan di chunk sebelumnya
+	pendingIDs := make(map[string]bool, len(reqs))
+	for _, r := range reqs {
+		pendingIDs[r.ID] = true
+	}
+	for i := 0; i < n; i++ {
+		for _, d := range reqs[i].DependsOn {
+			if pendingIDs[d] && !inChunk[d] && i > 0 {
+				return reqs[:i]
+			}
+		}
+	}
+	return reqs[:n]
+}
+
+// sendBatch mengirim satu POST /$batch dan menyimpan hasilnya ke results.
+func sendBatch(chunk []BatchRequest, results map[string]*BatchResponse) error {
+	inChunk := make(map[string]bool, len(chunk))
+	wire := make([]batchWireRequest, 0, len(chunk))
+
+	for _, r := range chunk {
+		w := batchWireRequest{ID: r.ID, Method: r.Method, URL: r.URL, Body: r.Body}
+		if r.Body != nil {
+			w.Headers = map[string]string{"Content-Type": "application/json"}
+		}
+
+		failedDep := ""
+		for _, d := range r.DependsOn {
+			if inChunk[d] {
+				w.DependsOn = append(w.DependsOn, d)
+			} else if prev, ok := results[d]; ok && prev.Err != nil {
+				failedDep = d
+			}
+		}
+		if failedDep != "" {
+			results[r.ID] = &BatchResponse{
+				ID:     r.ID,
+				Status: http.StatusFailedDependency,
+				Err:    &GraphError{Op: r.Method + " " + r.URL, StatusCode: http.StatusFailedDependency, Message: "dependensi " + failedDep + " gagal"},
+			}
+			continue
+		}
+
+		inChunk[r.ID] = true
+		wire = append(wire, w)
+	}
+	if len(wire) == 0 {
+		return nil
+	}
+
+	token, err := GetToken()
+	if err != nil {
+		return err
+	}
+
+	var out struct {
+		Responses []batchWireResponse `json:"responses"`
+	}
+	resp, err := client().R().
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(map[string]interface{}{"requests": wire}).
+		Post(graphBaseURL() + "/$batch")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return responseError("batch", resp)
+	}
+	if err := json.Unmarshal(resp.Body(), &out); err != nil {
+		return fmt.Errorf("gagal decode respons batch: %w", err)
+	}
+
+	byID := make(map[string]batchWireRequest, len(wire))
+	for _, w := range wire {
+		byID[w.ID] = w
+	}
+	for _, r := range out.Responses {
+		res := &BatchResponse{ID: r.ID, Status: r.Status, Body: r.Body}
+		if r.Status >= 400 {
+			header := make(http.Header, len(r.Headers))
+			for k, v := range r.Headers {
+				header.Set(k, v)
+			}
+			w := byID[r.ID]
+			res.Err = newGraphError(w.Method+" "+w.URL, r.Status, header, r.Body)
+		}
+		results[r.ID] = res
+	}
+	for _, w := range wire {
+		if _, ok := results[w.ID]; !ok {
+			results[w.ID] = &BatchResponse{ID: w.ID, Status: http.StatusBadGateway,
+				Err: &GraphError{Op: w.Method + " " + w.URL, StatusCode: http.StatusBadGateway, Message: "tidak ada di respons batch"}}
+		}
+	}
+	return nil
+}
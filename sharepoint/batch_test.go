@@ -0,0 +1,74 @@
+package sharepoint_test
+
+import (
+	"converter_blob/sharepoint"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExecuteBatchDependsOnFailure(t *testing.T) {
+	srv := newServer(t)
+	srv.PutFile("Arsip/ada.pdf", []byte("isi"))
+
+	reqs := []sharepoint.BatchRequest{
+		sharepoint.DriveItemRequest("hilang", "Arsip/hilang.pdf"),
+		sharepoint.DriveItemRequest("ada", "Arsip/ada.pdf"),
+	}
+	dep := sharepoint.DriveItemRequest("tergantung", "Arsip/ada.pdf")
+	dep.DependsOn = []string{"hilang"}
+	reqs = append(reqs, dep)
+
+	results, err := sharepoint.ExecuteBatch(reqs)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if r := results["hilang"]; r.Status != http.StatusNotFound || r.Err == nil {
+		t.Errorf("hilang: status %d, err %v; seharusnya 404", r.Status, r.Err)
+	}
+	if r := results["ada"]; r.Err != nil {
+		t.Errorf("ada: %v", r.Err)
+	}
+	if r := results["tergantung"]; r.Status != http.StatusFailedDependency || r.Err == nil {
+		t.Errorf("tergantung: status %d, err %v; seharusnya 424", r.Status, r.Err)
+	}
+}
+
+func TestExecuteBatchDependsOnAcrossChunks(t *testing.T) {
+	srv := newServer(t)
+	srv.PutFile("Arsip/ada.pdf", []byte("isi"))
+
+	// "a" gagal di chunk pertama; "z" bergantung pada "a" tapi baru terkirim di chunk berikutnya,
+	// jadi 424-nya harus dibuat di sisi klien
+	reqs := []sharepoint.BatchRequest{sharepoint.DriveItemRequest("a", "Arsip/hilang.pdf")}
+	for i := 0; i < 20; i++ {
+		reqs = append(reqs, sharepoint.DriveItemRequest(fmt.Sprintf("isi-%d", i), "Arsip/ada.pdf"))
+	}
+	z := sharepoint.DriveItemRequest("z", "Arsip/ada.pdf")
+	z.DependsOn = []string{"a"}
+	reqs = append(reqs, z)
+
+	results, err := sharepoint.ExecuteBatch(reqs)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("hasil %d, seharusnya %d", len(results), len(reqs))
+	}
+	r := results["z"]
+	if r.Status != http.StatusFailedDependency || r.Err == nil || !strings.Contains(r.Err.Error(), "dependensi a gagal") {
+		t.Fatalf("z: status %d, err %v; seharusnya 424 karena dependensi a gagal", r.Status, r.Err)
+	}
+
+	// z tidak boleh ikut terkirim ke server
+	batched := 0
+	for _, req := range srv.Requests() {
+		if strings.HasPrefix(req, "$batch ") {
+			batched++
+		}
+	}
+	if batched != len(reqs)-1 {
+		t.Errorf("%d sub-request terkirim, seharusnya %d", batched, len(reqs)-1)
+	}
+}
@@ -0,0 +1,89 @@
+package sharepoint
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ConflictPolicy menentukan apa yang dilakukan upload kalau item dengan path yang sama sudah ada.
+type ConflictPolicy string
+
+const (
+	// ConflictReplace menimpa item lama (versi baru di riwayat versi SharePoint).
+	ConflictReplace ConflictPolicy = "replace"
+	// ConflictSkip melewati upload kalau ukuran dan QuickXorHash item sama; kalau beda, ditimpa.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictRename meng-upload dengan nama baru ("nama 1.pdf") dari SharePoint.
+	ConflictRename ConflictPolicy = "rename"
+	// ConflictFail gagal dengan 409 (IsConflict) kalau item sudah ada.
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictNewer hanya menimpa kalau ModifiedAt lebih baru dari item SharePoint;
+	// kalau salah satu tanggal tidak diketahui perilakunya sama dengan ConflictSkip.
+	ConflictNewer ConflictPolicy = "newer"
+)
+
+// ParseConflictPolicy membaca nilai --sp-conflict; kosong berarti replace.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return ConflictReplace, nil
+	case ConflictReplace, ConflictSkip, ConflictRename, ConflictFail, ConflictNewer:
+		return p, nil
+	}
+	return "", fmt.Errorf("conflict policy %q tidak dikenal (replace|skip|rename|fail|newer)", s)
+}
+
+// conflictBehavior adalah nilai @microsoft.graph.conflictBehavior untuk policy ini.
+// skip/newer sudah diputuskan sebelum session dibuat, jadi sisanya menimpa.
+func (p ConflictPolicy) conflictBehavior() string {
+	switch p {
+	case ConflictRename:
+		return "rename"
+	case ConflictFail:
+		return "fail"
+	}
+	return "replace"
+}
+
+// checkExisting dipanggil sebelum membuat upload session untuk skip/newer. Mengembalikan item
+// lama dengan Skipped terisi kalau upload tidak perlu, atau nil kalau upload tetap dilakukan.
+func checkExisting(token, driveID, escapedPath string, r io.ReaderAt, size int64, opts UploadOptions) (*ItemResponse, error) {
+	existing, err := getDriveItem(token, driveID, "root:/"+escapedPath)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if remote := existing.LastModified(); opts.Conflict == ConflictNewer && !opts.ModifiedAt.IsZero() && !remote.IsZero() {
+		if opts.ModifiedAt.After(remote) {
+			return nil, nil
+		}
+		existing.Skipped = fmt.Sprintf("SharePoint tidak lebih lama (%s >= %s)",
+			remote.UTC().Format("2006-01-02 15:04:05"), opts.ModifiedAt.UTC().Format("2006-01-02 15:04:05"))
+		return existing, nil
+	}
+
+	if existing.Size != size {
+		return nil, nil
+	}
+	remoteHash := existing.QuickXorHash()
+	if remoteHash == "" {
+		// tanpa hash, ukuran sama dianggap cukup supaya re-run tidak meng-upload ulang
+		existing.Skipped = "ukuran sama"
+		return existing, nil
+	}
+
+	h := NewQuickXorHash()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return nil, err
+	}
+	existing.LocalQuickXorHash = QuickXorHashString(h)
+	if existing.LocalQuickXorHash != remoteHash {
+		return nil, nil
+	}
+	existing.Skipped = "ukuran dan quickXorHash sama"
+	return existing, nil
+}
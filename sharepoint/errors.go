@@ -0,0 +1,161 @@
+package sharepoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// GraphError adalah respons gagal dari Graph atau SharePoint REST. Pakai IsConflict, IsThrottled,
+// IsAuth, IsNotFound dan IsQuotaExceeded untuk bercabang, bukan mencocokkan teks error.
+type GraphError struct {
+	Op         string        // operasi yang gagal, mis. "upload" atau "GET /drives/..."
+	StatusCode int           // HTTP status
+	Code       string        // error.code dari Graph (mis. nameAlreadyExists), bisa kosong
+	Message    string        // error.message, atau body mentah kalau bukan JSON
+	RequestID  string        // request-id untuk tiket ke Microsoft
+	RetryAfter time.Duration // dari header Retry-After, 0 kalau tidak ada
+}
+
+func (e *GraphError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s gagal status %d", e.Op, e.StatusCode)
+	if e.Code != "" {
+		fmt.Fprintf(&b, " (%s)", e.Code)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " [request-id %s]", e.RequestID)
+	}
+	return b.String()
+}
+
+// newGraphError membentuk GraphError dari status, header, dan body respons.
+func newGraphError(op string, status int, header http.Header, body []byte) *GraphError {
+	e := &GraphError{
+		Op:         op,
+		StatusCode: status,
+		RequestID:  header.Get("request-id"),
+	}
+	if e.RequestID == "" {
+		e.RequestID = header.Get("SPRequestGuid")
+	}
+
+	if ra := header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(ra); err == nil {
+			e.RetryAfter = time.Until(t)
+		}
+	}
+
+	// Graph: {"error":{"code","message","innerError":{"request-id"}}}
+	// SharePoint REST (nometadata): {"odata.error":{"code","message":{"value"}}}
+	var payload struct {
+		Error *struct {
+			Code       string `json:"code"`
+			Message    string `json:"message"`
+			InnerError struct {
+				RequestID string `json:"request-id"`
+			} `json:"innerError"`
+		} `json:"error"`
+		ODataError *struct {
+			Code    string `json:"code"`
+			Message struct {
+				Value string `json:"value"`
+			} `json:"message"`
+		} `json:"odata.error"`
+	}
+	switch {
+	case json.Unmarshal(body, &payload) != nil:
+		e.Message = strings.TrimSpace(string(body))
+	case payload.Error != nil:
+		e.Code = payload.Error.Code
+		e.Message = payload.Error.Message
+		if e.RequestID == "" {
+			e.RequestID = payload.Error.InnerError.RequestID
+		}
+	case payload.ODataError != nil:
+		e.Code = payload.ODataError.Code
+		e.Message = payload.ODataError.Message.Value
+	default:
+		e.Message = strings.TrimSpace(string(body))
+	}
+
+	return e
+}
+
+// responseError membentuk GraphError dari respons resty.
+func responseError(op string, resp *resty.Response) *GraphError {
+	return newGraphError(op, resp.StatusCode(), resp.Header(), resp.Body())
+}
+
+// AsGraphError mengambil *GraphError dari rantai error.
+func AsGraphError(err error) (*GraphError, bool) {
+	var ge *GraphError
+	ok := errors.As(err, &ge)
+	return ge, ok
+}
+
+// StatusCode mengembalikan HTTP status dari err, 0 kalau err bukan GraphError.
+func StatusCode(err error) int {
+	if ge, ok := AsGraphError(err); ok {
+		return ge.StatusCode
+	}
+	return 0
+}
+
+// IsConflict bernilai true kalau item dengan nama yang sama sudah ada.
+func IsConflict(err error) bool {
+	ge, ok := AsGraphError(err)
+	return ok && (ge.StatusCode == http.StatusConflict || ge.Code == "nameAlreadyExists")
+}
+
+// IsThrottled bernilai true untuk 429 atau 503 (Graph memakai keduanya untuk throttling).
+func IsThrottled(err error) bool {
+	ge, ok := AsGraphError(err)
+	return ok && (ge.StatusCode == http.StatusTooManyRequests || ge.StatusCode == http.StatusServiceUnavailable)
+}
+
+// IsAuth bernilai true kalau token ditolak atau aplikasi tidak punya izin.
+func IsAuth(err error) bool {
+	ge, ok := AsGraphError(err)
+	return ok && (ge.StatusCode == http.StatusUnauthorized || ge.StatusCode == http.StatusForbidden)
+}
+
+// IsNotFound bernilai true kalau item/user/group tidak ditemukan.
+func IsNotFound(err error) bool {
+	ge, ok := AsGraphError(err)
+	return ok && (ge.StatusCode == http.StatusNotFound || ge.Code == "itemNotFound")
+}
+
+// IsQuotaExceeded bernilai true kalau kuota penyimpanan site/tenant habis.
+func IsQuotaExceeded(err error) bool {
+	ge, ok := AsGraphError(err)
+	return ok && (ge.StatusCode == http.StatusInsufficientStorage || ge.Code == "quotaLimitReached")
+}
+
+// HashMismatchError: isi item di SharePoint tidak sama dengan byte yang di-upload.
+type HashMismatchError struct {
+	Path   string
+	Local  string // QuickXorHash dari byte yang dikirim
+	Remote string // file.hashes.quickXorHash dari drive item
+}
+
+func (e *HashMismatchError) Error() string {
+	return fmt.Sprintf("verifikasi hash %s gagal: quickXorHash lokal %s != SharePoint %s", e.Path, e.Local, e.Remote)
+}
+
+// IsHashMismatch bernilai true kalau upload selesai tapi hash SharePoint berbeda.
+func IsHashMismatch(err error) bool {
+	var he *HashMismatchError
+	return errors.As(err, &he)
+}
@@ -0,0 +1,257 @@
+package sharepoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldMap memetakan satu kolom teradocu.document_metadata ke kolom list SharePoint.
+type FieldMap struct {
+	Column string `json:"column"`
+	Field  string `json:"field"`
+	// Type: text (default), number, boolean, datetime, atau user (email → kolom Person, mis.
+	// Author/Editor; "XxxLookupId" dibaca sebagai kolom Xxx)
+	Type string `json:"type,omitempty"`
+}
+
+// peopleFields adalah kolom sistem Person yang tidak bisa di-PATCH lewat Graph tapi bisa diset
+// dengan ValidateUpdateListItem di SharePoint REST.
+var peopleFields = map[string]bool{"Author": true, "Editor": true}
+
+// systemFields adalah kolom bawaan SharePoint yang tidak bisa diisi dari mapping.
+var systemFields = map[string]string{
+	"created":          "tanggal dibuat diisi otomatis dari doc_meta.created_date",
+	"modified":         "tanggal modified diisi otomatis dari doc_meta.modified_date",
+	"authorlookupid":   `pakai {"field": "Author", "type": "user"}`,
+	"editorlookupid":   `pakai {"field": "Editor", "type": "user"}`,
+	"id":               "",
+	"fileleafref":      "nama file mengikuti path upload",
+	"fileref":          "",
+	"filedirref":       "",
+	"file_x0020_size":  "",
+	"filesizedisplay":  "",
+	"contenttype":      "",
+	"contenttypeid":    "",
+	"_uiversionstring": "",
+	"docicon":          "",
+	"itemchildcount":   "",
+	"folderchildcount": "",
+	"checkoutuser":     "",
+}
+
+type FieldMapping struct {
+	Fields []FieldMap `json:"fields"`
+}
+
+// LoadFieldMapping membaca file mapping JSON, mis:
+//
+//	{"fields": [
+//	  {"column": "file_type", "field": "TeradocuFileType"},
+//	  {"column": "owner", "field": "Author", "type": "user"}
+//	]}
+//
+// Kolom sistem (Created, Modified, AuthorLookupId, ...) ditolak; lihat systemFields.
+func LoadFieldMapping(path string) (*FieldMapping, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca mapping kolom %s: %w", path, err)
+	}
+
+	var m FieldMapping
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("gagal decode mapping kolom %s: %w", path, err)
+	}
+
+	for i, f := range m.Fields {
+		if f.Column == "" || f.Field == "" {
+			return nil, fmt.Errorf("mapping kolom #%d: column dan field wajib diisi", i+1)
+		}
+		switch f.Type {
+		case "", "text", "number", "boolean", "datetime", "user":
+		default:
+			return nil, fmt.Errorf("mapping kolom %s: type %q tidak dikenal", f.Column, f.Type)
+		}
+		if hint, ok := systemFields[strings.ToLower(f.Field)]; ok {
+			if hint == "" {
+				hint = "kolom sistem tidak bisa diisi"
+			}
+			return nil, fmt.Errorf("mapping kolom %s: field %s read-only di SharePoint (%s)", f.Column, f.Field, hint)
+		}
+		if peopleFields[f.Field] && f.Type != "user" {
+			return nil, fmt.Errorf("mapping kolom %s: field %s harus type user", f.Column, f.Field)
+		}
+	}
+
+	return &m, nil
+}
+
+// Build mengubah satu baris metadata menjadi nilai form ValidateUpdateListItem (field → teks)
+// untuk UpdateItemValues. Kolom yang NULL dilewati supaya nilai default SharePoint tidak
+// tertimpa kosong.
+func (m *FieldMapping) Build(row map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for _, f := range m.Fields {
+		v, ok := row[f.Column]
+		if !ok {
+			return nil, fmt.Errorf("kolom %s tidak ada di document_metadata", f.Column)
+		}
+		if v == nil {
+			continue
+		}
+
+		switch f.Type {
+		case "datetime":
+			t, ok := v.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("kolom %s bukan timestamp (%T)", f.Column, v)
+			}
+			values[f.Field] = FieldDateValue(t)
+		case "number":
+			switch n := v.(type) {
+			case int64:
+				values[f.Field] = strconv.FormatInt(n, 10)
+			case float64:
+				values[f.Field] = strconv.FormatFloat(n, 'f', -1, 64)
+			case string:
+				x, err := strconv.ParseFloat(n, 64)
+				if err != nil {
+					return nil, fmt.Errorf("kolom %s bukan angka: %q", f.Column, n)
+				}
+				values[f.Field] = strconv.FormatFloat(x, 'f', -1, 64)
+			default:
+				return nil, fmt.Errorf("kolom %s bukan angka (%T)", f.Column, v)
+			}
+		case "boolean":
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("kolom %s bukan boolean (%T)", f.Column, v)
+			}
+			values[f.Field] = "0"
+			if b {
+				values[f.Field] = "1"
+			}
+		case "user":
+			email := strings.TrimSpace(fmt.Sprint(v))
+			if email == "" {
+				continue
+			}
+			value, err := personValue(email)
+			if err != nil {
+				return nil, fmt.Errorf("kolom %s: %w", f.Column, err)
+			}
+			values[strings.TrimSuffix(f.Field, "LookupId")] = value
+		default:
+			if t, ok := v.(time.Time); ok {
+				values[f.Field] = t.Format(time.RFC3339)
+			} else {
+				values[f.Field] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// fieldDateLayout adalah format tanggal yang dibaca SharePoint untuk semua regional settings.
+const fieldDateLayout = "2006-01-02 15:04:05"
+
+// siteLocation adalah zona waktu site (SP_TIME_ZONE, nama IANA mis. Asia/Jakarta; default zona
+// lokal). ValidateUpdateListItem membaca tanggal sebagai waktu di zona regional settings site.
+func siteLocation() *time.Location {
+	if tz := os.Getenv("SP_TIME_ZONE"); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// FieldDateValue memformat t sebagai nilai kolom tanggal ValidateUpdateListItem.
+func FieldDateValue(t time.Time) string {
+	return t.In(siteLocation()).Format(fieldDateLayout)
+}
+
+// ParseFieldDateValue adalah kebalikan FieldDateValue.
+func ParseFieldDateValue(v string) (time.Time, error) {
+	return time.ParseInLocation(fieldDateLayout, v, siteLocation())
+}
+
+// personValue adalah nilai kolom Person ValidateUpdateListItem untuk user Entra ID.
+func personValue(email string) (string, error) {
+	b, err := json.Marshal([]map[string]string{{"Key": "i:0#.f|membership|" + strings.ToLower(email)}})
+	return string(b), err
+}
+
+// UpdateItemFields mengisi kolom list SharePoint untuk drive item lewat Graph. SharePoint
+// menganggapnya edit biasa: Modified menjadi waktu sekarang dan library dengan versioning
+// mendapat versi baru, jadi metadata hasil migrasi memakai UpdateItemValues.
+func UpdateItemFields(itemID string, fields map[string]interface{}) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	token, err := GetToken()
+	if err != nil {
+		return err
+	}
+	driveID := os.Getenv("MS_DRIVE_ID")
+	if driveID == "" {
+		return fmt.Errorf("❌ MS_DRIVE_ID belum diset")
+	}
+
+	fieldsURL := fmt.Sprintf("%s/drives/%s/items/%s/listItem/fields", graphBaseURL(), driveID, itemID)
+
+	resp, err := client().R().
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(fields).
+		Patch(fieldsURL)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return responseError("update kolom", resp)
+	}
+
+	return nil
+}
+
+// UpdateItemFieldsRequest adalah UpdateItemFields sebagai sub-request batch.
+func UpdateItemFieldsRequest(id, itemID string, fields map[string]interface{}) BatchRequest {
+	path := fmt.Sprintf("/drives/%s/items/%s/listItem/fields", os.Getenv("MS_DRIVE_ID"), itemID)
+	return BatchRequest{ID: id, Method: "PATCH", URL: path, Body: fields}
+}
+
+// UpdateItemFieldsBatch mengisi kolom banyak item sekaligus lewat /$batch (itemID → fields).
+// Mengembalikan error per item yang gagal; error kedua berarti panggilan batch sendiri gagal.
+func UpdateItemFieldsBatch(updates map[string]map[string]interface{}) (map[string]error, error) {
+	if os.Getenv("MS_DRIVE_ID") == "" {
+		return nil, fmt.Errorf("❌ MS_DRIVE_ID belum diset")
+	}
+
+	reqs := make([]BatchRequest, 0, len(updates))
+	for itemID, fields := range updates {
+		if len(fields) == 0 {
+			continue
+		}
+		reqs = append(reqs, UpdateItemFieldsRequest(itemID, itemID, fields))
+	}
+
+	results, err := ExecuteBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make(map[string]error)
+	for _, r := range reqs {
+		if res := results[r.ID]; res.Err != nil {
+			failed[r.ID] = res.Err
+		}
+	}
+	return failed, nil
+}
@@ -0,0 +1,95 @@
+package sharepoint_test
+
+import (
+	"bytes"
+	"converter_blob/sharepoint"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadMapping(t *testing.T, body string) *sharepoint.FieldMapping {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "sp_fields.json")
+	if err := os.WriteFile(file, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := sharepoint.LoadFieldMapping(file)
+	if err != nil {
+		t.Fatalf("mapping: %v", err)
+	}
+	return m
+}
+
+func TestUpdateItemValuesKeepsModified(t *testing.T) {
+	srv := newServer(t)
+	created := time.Date(2019, 3, 4, 8, 30, 0, 0, time.UTC)
+	modified := time.Date(2021, 6, 7, 14, 15, 16, 0, time.UTC)
+
+	data := []byte("isi dokumen")
+	item, err := sharepoint.UploadReaderAt(bytes.NewReader(data), int64(len(data)), "Arsip/dok.pdf", sharepoint.UploadOptions{
+		CreatedAt:  created,
+		ModifiedAt: modified,
+	})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if !item.LastModified().Equal(modified) {
+		t.Fatalf("lastModifiedDateTime setelah upload %s, seharusnya %s", item.LastModified(), modified)
+	}
+
+	mapping := loadMapping(t, `{"fields": [
+		{"column": "file_type", "field": "TeradocuFileType"},
+		{"column": "version", "field": "TeradocuVersion", "type": "number"},
+		{"column": "created_by", "field": "Author", "type": "user"}
+	]}`)
+	values, err := mapping.Build(map[string]interface{}{
+		"file_type":  "Kontrak",
+		"version":    int64(3),
+		"created_by": "Budi@Example.com",
+	})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if err := sharepoint.UpdateItemValues(item.ID, values, created, modified); err != nil {
+		t.Fatalf("update kolom: %v", err)
+	}
+
+	got, err := sharepoint.GetDriveItem("Arsip/dok.pdf")
+	if err != nil {
+		t.Fatalf("ambil item: %v", err)
+	}
+	if !got.LastModified().Equal(modified) {
+		t.Errorf("lastModifiedDateTime setelah update kolom %s, seharusnya tetap %s", got.LastModified(), modified)
+	}
+
+	stored, _ := srv.Item("Arsip/dok.pdf")
+	want := map[string]string{
+		"TeradocuFileType": "Kontrak",
+		"TeradocuVersion":  "3",
+		"Author":           `[{"Key":"i:0#.f|membership|budi@example.com"}]`,
+		"Created":          sharepoint.FieldDateValue(created),
+	}
+	for field, v := range want {
+		if stored.Fields[field] != v {
+			t.Errorf("kolom %s = %v, seharusnya %q", field, stored.Fields[field], v)
+		}
+	}
+}
+
+func TestLoadFieldMappingRejectsSystemFields(t *testing.T) {
+	for _, body := range []string{
+		`{"fields": [{"column": "modified_date", "field": "Modified", "type": "datetime"}]}`,
+		`{"fields": [{"column": "owner", "field": "AuthorLookupId", "type": "user"}]}`,
+		`{"fields": [{"column": "owner", "field": "Author"}]}`,
+	} {
+		file := filepath.Join(t.TempDir(), "sp_fields.json")
+		if err := os.WriteFile(file, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sharepoint.LoadFieldMapping(file); err == nil {
+			t.Errorf("mapping %s seharusnya ditolak", body)
+		}
+	}
+}
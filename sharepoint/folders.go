@@ -0,0 +1,144 @@
+package sharepoint
+
+import (
+	"converter_blob/pathmap"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ensureFolderRounds membatasi putaran lookup+create; putaran berikutnya hanya untuk folder
+// yang gagal karena dependensi (parent 409) atau dibuat proses lain di saat yang sama.
+const ensureFolderRounds = 3
+
+func createFolderRequest(id, parentPath, name string) BatchRequest {
+	body := map[string]interface{}{
+		"name":                              pathmap.SanitizeName(name),
+		"folder":                            map[string]interface{}{},
+		"@microsoft.graph.conflictBehavior": "fail",
+	}
+	return BatchRequest{ID: id, Method: "POST", URL: drivePathURL(parentPath) + childrenSuffix(parentPath), Body: body}
+}
+
+// childrenSuffix: root memakai /root/children, path memakai /root:/a/b:/children.
+func childrenSuffix(parentPath string) string {
+	if pathmap.EscapePath(parentPath) == "" {
+		return "/children"
+	}
+	return ":/children"
+}
+
+// CleanFolderPath menormalkan path folder seperti key hasil EnsureFolders ("a/b").
+func CleanFolderPath(p string) string {
+	return strings.Trim(path.Clean("/"+strings.ReplaceAll(p, "\\", "/")), "/")
+}
+
+// folderChain mengembalikan path beserta semua parent-nya, terurut dari yang paling dangkal.
+func folderChain(paths []string) []string {
+	set := make(map[string]bool)
+	for _, p := range paths {
+		p = CleanFolderPath(p)
+		for p != "" && p != "." && !set[p] {
+			set[p] = true
+			p = path.Dir(p)
+		}
+	}
+	list := make([]string, 0, len(set))
+	for p := range set {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		di, dj := strings.Count(list[i], "/"), strings.Count(list[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return list[i] < list[j]
+	})
+	return list
+}
+
+// EnsureFolders memastikan semua folder (dan parent-nya) ada di MS_DRIVE_ID lewat /$batch,
+// per kedalaman (breadth-first): lookup dulu, lalu yang belum ada dibuat dengan dependsOn ke
+// parent yang dibuat di batch yang sama. Nama folder disanitasi dengan pathmap.SanitizeName.
+// Mengembalikan drive item id per path yang berhasil dan error per path yang gagal; key
+// keduanya adalah path yang dinormalisasi ("a/b", tanpa "/" di depan atau belakang).
+func EnsureFolders(paths []string) (map[string]string, map[string]error, error) {
+	if os.Getenv("MS_DRIVE_ID") == "" {
+		return nil, nil, fmt.Errorf("❌ MS_DRIVE_ID belum diset")
+	}
+
+	ids := make(map[string]string)
+	failed := make(map[string]error)
+	todo := folderChain(paths)
+
+	for round := 0; round < ensureFolderRounds && len(todo) > 0; round++ {
+		// 1. lookup
+		lookups := make([]BatchRequest, 0, len(todo))
+		for i, p := range todo {
+			lookups = append(lookups, DriveItemRequest(strconv.Itoa(i), p))
+		}
+		found, err := ExecuteBatch(lookups)
+		if err != nil {
+			return ids, failed, err
+		}
+
+		var missing []string
+		for i, p := range todo {
+			res := found[strconv.Itoa(i)]
+			var item ItemResponse
+			switch err := res.Decode(&item); {
+			case err == nil:
+				ids[p] = item.ID
+				delete(failed, p)
+			case IsNotFound(err):
+				missing = append(missing, p)
+			default:
+				failed[p] = err
+			}
+		}
+
+		// 2. create; parent yang ikut dibuat jadi dependsOn
+		creates := make([]BatchRequest, 0, len(missing))
+		reqID := make(map[string]string, len(missing))
+		for i, p := range missing {
+			id := strconv.Itoa(i)
+			reqID[p] = id
+			parent := path.Dir(p)
+			if parent == "." {
+				parent = ""
+			}
+			r := createFolderRequest(id, parent, path.Base(p))
+			if pid, ok := reqID[parent]; ok {
+				r.DependsOn = []string{pid}
+			}
+			creates = append(creates, r)
+		}
+		created, err := ExecuteBatch(creates)
+		if err != nil {
+			return ids, failed, err
+		}
+
+		todo = nil
+		for _, p := range missing {
+			res := created[reqID[p]]
+			var item ItemResponse
+			switch err := res.Decode(&item); {
+			case err == nil:
+				ids[p] = item.ID
+				delete(failed, p)
+			case IsConflict(err) || res.Status == http.StatusFailedDependency:
+				// dibuat proses lain, atau parent-nya 409: lookup ulang di putaran berikutnya
+				failed[p] = err
+				todo = append(todo, p)
+			default:
+				failed[p] = err
+			}
+		}
+	}
+
+	return ids, failed, nil
+}
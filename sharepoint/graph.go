@@ -0,0 +1,98 @@
+package sharepoint
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultGraphBaseURL = "https://graph.microsoft.com/v1.0"
+	defaultLoginBaseURL = "https://login.microsoftonline.com"
+)
+
+var (
+	baseURLMu     sync.RWMutex
+	graphOverride string
+	loginOverride string
+)
+
+// SetBaseURLs mengganti base URL Graph (termasuk /v1.0) dan login AAD, mis. ke server graphtest.
+// String kosong berarti kembali ke MS_GRAPH_BASE_URL / MS_LOGIN_BASE_URL atau default Microsoft.
+func SetBaseURLs(graph, login string) {
+	baseURLMu.Lock()
+	defer baseURLMu.Unlock()
+	graphOverride = strings.TrimRight(graph, "/")
+	loginOverride = strings.TrimRight(login, "/")
+}
+
+func baseURL(override, env, def string) string {
+	if override != "" {
+		return override
+	}
+	if v := os.Getenv(env); v != "" {
+		return strings.TrimRight(v, "/")
+	}
+	return def
+}
+
+func graphBaseURL() string {
+	baseURLMu.RLock()
+	defer baseURLMu.RUnlock()
+	return baseURL(graphOverride, "MS_GRAPH_BASE_URL", defaultGraphBaseURL)
+}
+
+func loginBaseURL() string {
+	baseURLMu.RLock()
+	defer baseURLMu.RUnlock()
+	return baseURL(loginOverride, "MS_LOGIN_BASE_URL", defaultLoginBaseURL)
+}
+
+// graphGet melakukan GET ke Graph (path relatif ke /v1.0) dan decode JSON ke out.
+func graphGet(path string, out interface{}) error {
+	token, err := GetToken()
+	if err != nil {
+		return err
+	}
+
+	resp, err := client().R().
+		SetHeader("Authorization", "Bearer "+token).
+		Get(graphBaseURL() + path)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return responseError("GET "+path, resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Body(), out)
+}
+
+// graphSend mengirim body JSON dengan method tertentu dan decode respons ke out (boleh nil).
+func graphSend(method, path string, body, out interface{}) error {
+	token, err := GetToken()
+	if err != nil {
+		return err
+	}
+
+	req := client().R().
+		SetHeader("Authorization", "Bearer "+token)
+	if body != nil {
+		req = req.SetHeader("Content-Type", "application/json").SetBody(body)
+	}
+
+	resp, err := req.Execute(method, graphBaseURL()+path)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return responseError(method+" "+path, resp)
+	}
+	if out == nil || len(resp.Body()) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Body(), out)
+}
@@ -0,0 +1,838 @@
+// Package graphtest adalah server Microsoft Graph palsu (berbasis httptest) untuk menjalankan
+// upload dan sinkronisasi permission secara lokal tanpa tenant Microsoft 365.
+//
+// Yang didukung: token client credentials, createUploadSession + PUT chunk dengan
+// nextExpectedRanges, PUT /content, lookup item per path/id, buat folder lewat children,
+// listItem/fields, ValidateUpdateListItem SharePoint REST (di SiteURL), permissions
+// (list/invite/patch/delete), dan JSON batching /$batch
+// (sub-request dijalankan berurutan, dependsOn yang gagal menjadi 424). Fault (429/5xx,
+// chunk yang hanya diterima sebagian atau disimpan rusak) bisa disuntikkan lewat AddFault,
+// juga ke sub-request batch. Item file membawa file.hashes.quickXorHash seperti SharePoint.
+package graphtest
+
+import (
+	"context"
+	"converter_blob/sharepoint"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultToken = "graphtest-token"
+	DriveID      = "graphtest-drive"
+	SiteID       = "graphtest-site"
+	ListID       = "00000000-0000-0000-0000-0000000000aa"
+)
+
+// Item adalah file atau folder di drive palsu.
+type Item struct {
+	ID          string
+	Name        string
+	Path        string // path relatif ke root drive, tanpa "/" di depan
+	Folder      bool
+	Content     []byte
+	Modified    time.Time
+	Fields      map[string]interface{}
+	Permissions []*Permission
+}
+
+// Permission adalah grant di item palsu.
+type Permission struct {
+	ID        string
+	Roles     []string
+	Email     string
+	UserID    string
+	GroupID   string
+	Inherited bool
+}
+
+// Fault adalah gangguan yang disuntikkan ke request yang cocok.
+type Fault struct {
+	Method     string // kosong = semua method
+	Path       string // substring path request, kosong = semua
+	Status     int    // status error yang dikembalikan (429, 500, 503, ...)
+	RetryAfter int    // detik untuk header Retry-After, 0 = tanpa header
+	Partial    bool   // khusus PUT chunk: hanya separuh pertama chunk yang diterima
+	Corrupt    bool   // khusus PUT chunk: satu byte chunk diubah sebelum disimpan (hash jadi beda)
+	Count      int    // berapa kali fault berlaku, 0 dianggap 1
+}
+
+type session struct {
+	path     string
+	conflict string
+	modified time.Time
+	data     []byte
+	total    int64
+}
+
+// Server adalah server Graph palsu. URL-nya dipakai lewat GraphURL dan LoginURL.
+type Server struct {
+	*httptest.Server
+	Token string
+
+	mu       sync.Mutex
+	items    map[string]*Item // path lowercase → item
+	byID     map[string]*Item
+	sessions map[string]*session
+	faults   []*Fault
+	seq      int
+	requests []string
+}
+
+// NewServer membuat dan menjalankan server palsu di port acak.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer membuat server tanpa menjalankannya (Listener boleh diganti dulu).
+func NewUnstartedServer() *Server {
+	s := &Server{
+		Token:    DefaultToken,
+		items:    make(map[string]*Item),
+		byID:     make(map[string]*Item),
+		sessions: make(map[string]*session),
+	}
+	s.items[""] = &Item{ID: "root", Name: "root", Folder: true}
+	s.byID["root"] = s.items[""]
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *Server) GraphURL() string { return s.URL + "/v1.0" }
+func (s *Server) LoginURL() string { return s.URL + "/login" }
+func (s *Server) SiteURL() string  { return s.URL + "/sites/graphtest" }
+
+// Use mengarahkan package sharepoint ke server ini: base URL, kredensial palsu, drive, site dan
+// SP_SITE_URL.
+func (s *Server) Use() {
+	sharepoint.SetBaseURLs(s.GraphURL(), s.LoginURL())
+	sharepoint.SetTokenProvider(&sharepoint.ClientSecretCredential{
+		TenantID: "graphtest",
+		ClientID: "graphtest",
+		Secret:   "graphtest",
+	})
+	os.Setenv("MS_DRIVE_ID", DriveID)
+	os.Setenv("MS_SITE_ID", SiteID)
+	os.Setenv("SP_SITE_URL", s.SiteURL())
+}
+
+// AddFault menambahkan gangguan untuk request berikutnya yang cocok.
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f.Count <= 0 {
+		f.Count = 1
+	}
+	s.faults = append(s.faults, &f)
+}
+
+// PutFile menaruh file langsung di drive (mis. untuk menguji konflik).
+func (s *Server) PutFile(p string, content []byte) *Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.ensure(p, false)
+	it.Content = append([]byte(nil), content...)
+	it.Modified = time.Now()
+	return it
+}
+
+// AddPermission menambahkan grant user ke item di path (folder dibuat kalau belum ada).
+func (s *Server) AddPermission(p, email, role string, inherited bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(p)
+	if it == nil {
+		it = s.ensure(p, true)
+	}
+	it.Permissions = append(it.Permissions, &Permission{
+		ID:        s.nextID("perm"),
+		Roles:     []string{role},
+		Email:     strings.ToLower(email),
+		UserID:    "user-" + strings.ToLower(email),
+		Inherited: inherited,
+	})
+}
+
+// Item mengembalikan salinan item di path.
+func (s *Server) Item(p string) (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(p)
+	if it == nil {
+		return Item{}, false
+	}
+	return *it, true
+}
+
+// Requests mengembalikan log "METHOD path" semua request yang diterima.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ================= STORE =================
+
+func cleanPath(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s-%d", prefix, s.seq)
+}
+
+func (s *Server) lookup(p string) *Item {
+	return s.items[strings.ToLower(cleanPath(p))]
+}
+
+// ensure membuat item (dan semua folder parent) kalau belum ada.
+func (s *Server) ensure(p string, folder bool) *Item {
+	p = cleanPath(p)
+	if it := s.lookup(p); it != nil {
+		return it
+	}
+	if dir := path.Dir(p); dir != "." {
+		s.ensure(dir, true)
+	}
+	it := &Item{ID: s.nextID("item"), Name: path.Base(p), Path: p, Folder: folder}
+	s.items[strings.ToLower(p)] = it
+	s.byID[it.ID] = it
+	return it
+}
+
+// rename mencari nama bebas "nama 1.ext", "nama 2.ext", ... seperti conflictBehavior=rename.
+func (s *Server) rename(p string) string {
+	ext := path.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s %d%s", base, i, ext)
+		if s.lookup(candidate) == nil {
+			return candidate
+		}
+	}
+}
+
+// ================= HTTP =================
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("request-id", fmt.Sprintf("graphtest-%d", time.Now().UnixNano()))
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
+
+// takeFault mengambil fault pertama yang cocok dan mengurangi jatah pemakaiannya.
+func (s *Server) takeFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != "" && !strings.Contains(r.URL.Path, f.Path) {
+			continue
+		}
+		f.Count--
+		if f.Count <= 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.serve(w, r)
+}
+
+// serve memproses satu request (langsung atau sub-request $batch); s.mu sudah dipegang.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if f := s.takeFault(r); f != nil && !f.Partial && !f.Corrupt {
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+		}
+		writeError(w, f.Status, "graphtestFault", "fault disuntikkan")
+		return
+	} else if f != nil {
+		r = r.WithContext(withFault(r.Context(), f))
+	}
+
+	p := r.URL.Path
+	switch {
+	case strings.HasPrefix(p, "/login/") && strings.HasSuffix(p, "/oauth2/v2.0/token"):
+		s.handleToken(w, r)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusUnauthorized, "InvalidAuthenticationToken", "token tidak valid")
+		return
+	}
+
+	switch {
+	case p == "/v1.0/$batch" && r.Method == http.MethodPost:
+		s.handleBatch(w, r)
+	case p == "/v1.0/drives/"+DriveID+"/root/children" && r.Method == http.MethodPost:
+		s.createChild(w, r, "")
+	case strings.HasPrefix(p, "/upload/"):
+		s.handleUpload(w, r, strings.TrimPrefix(p, "/upload/"))
+	case strings.HasPrefix(p, "/v1.0/drives/"+DriveID+"/root:/"):
+		s.handleRootPath(w, r, strings.TrimPrefix(p, "/v1.0/drives/"+DriveID+"/root:/"))
+	case strings.HasPrefix(p, "/v1.0/sites/"+SiteID+"/drive/root:/"):
+		s.handleRootPath(w, r, strings.TrimPrefix(p, "/v1.0/sites/"+SiteID+"/drive/root:/"))
+	case strings.HasPrefix(p, "/v1.0/drives/"+DriveID+"/items/"):
+		s.handleItem(w, r, strings.Split(strings.TrimPrefix(p, "/v1.0/drives/"+DriveID+"/items/"), "/"))
+	case p == "/v1.0/drives/"+DriveID+"/list" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]string{"id": ListID})
+	case strings.HasPrefix(p, "/sites/graphtest/_api/web/lists(guid'"+ListID+"')/items("):
+		s.handleListItemREST(w, r, strings.TrimPrefix(p, "/sites/graphtest/_api/web/lists(guid'"+ListID+"')/items("))
+	default:
+		writeError(w, http.StatusNotFound, "itemNotFound", "endpoint tidak didukung graphtest: "+p)
+	}
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "unsupported_grant_type",
+			"error_description": "AADSTS70003: grant_type harus client_credentials",
+		})
+		return
+	}
+	if r.PostForm.Get("client_secret") == "" && r.PostForm.Get("client_assertion") == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{
+			"error":             "invalid_client",
+			"error_description": "AADSTS7000216: client_secret atau client_assertion wajib",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"access_token": s.Token,
+	})
+}
+
+func (s *Server) itemJSON(it *Item, host string) map[string]interface{} {
+	out := map[string]interface{}{
+		"id":     it.ID,
+		"name":   it.Name,
+		"size":   len(it.Content),
+		"webUrl": "http://" + host + "/drive/" + it.Path,
+		"parentReference": map[string]string{
+			"driveId": DriveID,
+			"path":    "/drive/root:/" + path.Dir(it.Path),
+		},
+	}
+	if it.Folder {
+		out["folder"] = map[string]interface{}{}
+	} else {
+		h := sharepoint.NewQuickXorHash()
+		h.Write(it.Content)
+		out["file"] = map[string]interface{}{
+			"mimeType": "application/octet-stream",
+			"hashes":   map[string]string{"quickXorHash": sharepoint.QuickXorHashString(h)},
+		}
+	}
+	if !it.Modified.IsZero() {
+		out["fileSystemInfo"] = map[string]string{"lastModifiedDateTime": it.Modified.UTC().Format(time.RFC3339)}
+	}
+	return out
+}
+
+// handleRootPath menangani /root:/{path}, /root:/{path}:/createUploadSession, /root:/{path}:/content
+// dan /root:/{path}:/children.
+func (s *Server) handleRootPath(w http.ResponseWriter, r *http.Request, rest string) {
+	switch {
+	case strings.HasSuffix(rest, ":/createUploadSession") && r.Method == http.MethodPost:
+		s.createSession(w, r, strings.TrimSuffix(rest, ":/createUploadSession"))
+	case strings.HasSuffix(rest, ":/content") && r.Method == http.MethodPut:
+		s.putContent(w, r, strings.TrimSuffix(rest, ":/content"))
+	case strings.HasSuffix(rest, ":/children") && r.Method == http.MethodPost:
+		s.createChild(w, r, strings.TrimSuffix(rest, ":/children"))
+	case r.Method == http.MethodGet:
+		it := s.lookup(strings.TrimSuffix(rest, ":"))
+		if it == nil {
+			writeError(w, http.StatusNotFound, "itemNotFound", "item tidak ditemukan")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.itemJSON(it, r.Host))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "invalidRequest", "method tidak didukung")
+	}
+}
+
+// resolveConflict menerapkan conflictBehavior; mengembalikan path tujuan atau false kalau ditolak.
+func (s *Server) resolveConflict(w http.ResponseWriter, p, behavior string) (string, bool) {
+	if s.lookup(p) == nil {
+		return p, true
+	}
+	switch behavior {
+	case "fail":
+		writeError(w, http.StatusConflict, "nameAlreadyExists", "item dengan nama yang sama sudah ada")
+		return "", false
+	case "rename":
+		return s.rename(p), true
+	}
+	return p, true
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request, p string) {
+	var body struct {
+		Item struct {
+			Conflict       string `json:"@microsoft.graph.conflictBehavior"`
+			FileSystemInfo struct {
+				LastModified time.Time `json:"lastModifiedDateTime"`
+			} `json:"fileSystemInfo"`
+		} `json:"item"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	conflict := body.Item.Conflict
+	if conflict == "" {
+		conflict = "fail"
+	}
+	if conflict == "fail" && s.lookup(p) != nil {
+		writeError(w, http.StatusConflict, "nameAlreadyExists", "item dengan nama yang sama sudah ada")
+		return
+	}
+
+	id := s.nextID("session")
+	s.sessions[id] = &session{path: cleanPath(p), conflict: conflict, modified: body.Item.FileSystemInfo.LastModified}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"uploadUrl":          "http://" + r.Host + "/upload/" + id,
+		"expirationDateTime": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		"nextExpectedRanges": []string{"0-"},
+	})
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request, id string) {
+	sess, ok := s.sessions[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "itemNotFound", "upload session tidak ditemukan")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"nextExpectedRanges": []string{fmt.Sprintf("%d-", len(sess.data))},
+		})
+		return
+	case http.MethodDelete:
+		delete(s.sessions, id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPut:
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "invalidRequest", "method tidak didukung")
+		return
+	}
+
+	var start, end, total int64
+	if _, err := fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidRange", "Content-Range tidak valid")
+		return
+	}
+	chunk, err := io.ReadAll(r.Body)
+	if err != nil || int64(len(chunk)) != end-start+1 {
+		writeError(w, http.StatusBadRequest, "invalidRange", "panjang body tidak sesuai Content-Range")
+		return
+	}
+
+	received := int64(len(sess.data))
+	if start > received {
+		writeError(w, http.StatusRequestedRangeNotSatisfiable, "invalidRange",
+			fmt.Sprintf("fragment dimulai di %d, seharusnya %d", start, received))
+		return
+	}
+	// bagian yang sudah diterima diabaikan
+	chunk = chunk[received-start:]
+	if f := faultFrom(r.Context()); f != nil && len(chunk) > 1 {
+		if f.Partial {
+			chunk = chunk[:len(chunk)/2]
+		}
+		if f.Corrupt {
+			chunk = append([]byte(nil), chunk...)
+			chunk[0] ^= 0xff
+		}
+	}
+	sess.data = append(sess.data, chunk...)
+	sess.total = total
+
+	if int64(len(sess.data)) < total {
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{
+			"expirationDateTime": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			"nextExpectedRanges": []string{fmt.Sprintf("%d-", len(sess.data))},
+		})
+		return
+	}
+
+	target, ok := s.resolveConflict(w, sess.path, sess.conflict)
+	if !ok {
+		return
+	}
+	delete(s.sessions, id)
+
+	it := s.ensure(target, false)
+	it.Content = sess.data
+	it.Modified = sess.modified
+	if it.Modified.IsZero() {
+		it.Modified = time.Now()
+	}
+	writeJSON(w, http.StatusCreated, s.itemJSON(it, r.Host))
+}
+
+func (s *Server) putContent(w http.ResponseWriter, r *http.Request, p string) {
+	conflict := r.URL.Query().Get("@microsoft.graph.conflictBehavior")
+	if conflict == "" {
+		conflict = "replace"
+	}
+	target, ok := s.resolveConflict(w, p, conflict)
+	if !ok {
+		return
+	}
+
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+		return
+	}
+
+	status := http.StatusCreated
+	if s.lookup(target) != nil {
+		status = http.StatusOK
+	}
+	it := s.ensure(target, false)
+	it.Content = content
+	it.Modified = time.Now()
+	writeJSON(w, status, s.itemJSON(it, r.Host))
+}
+
+// createChild membuat folder di bawah parent (harus sudah ada) sesuai conflictBehavior.
+func (s *Server) createChild(w http.ResponseWriter, r *http.Request, parent string) {
+	var body struct {
+		Name     string          `json:"name"`
+		Folder   json.RawMessage `json:"folder"`
+		Conflict string          `json:"@microsoft.graph.conflictBehavior"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" || body.Folder == nil {
+		writeError(w, http.StatusBadRequest, "invalidRequest", "name dan folder wajib diisi")
+		return
+	}
+	if s.lookup(parent) == nil {
+		writeError(w, http.StatusNotFound, "itemNotFound", "parent tidak ditemukan")
+		return
+	}
+
+	p := cleanPath(parent + "/" + body.Name)
+	if it := s.lookup(p); it != nil && body.Conflict == "replace" {
+		writeJSON(w, http.StatusOK, s.itemJSON(it, r.Host))
+		return
+	}
+	target, ok := s.resolveConflict(w, p, body.Conflict)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.itemJSON(s.ensure(target, true), r.Host))
+}
+
+// handleBatch menjalankan sub-request JSON batch berurutan; dependsOn yang gagal menghasilkan 424.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Requests []struct {
+			ID        string            `json:"id"`
+			Method    string            `json:"method"`
+			URL       string            `json:"url"`
+			Headers   map[string]string `json:"headers"`
+			Body      json.RawMessage   `json:"body"`
+			DependsOn []string          `json:"dependsOn"`
+		} `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+		return
+	}
+	if len(body.Requests) > 20 {
+		writeError(w, http.StatusBadRequest, "invalidRequest", "maksimal 20 request per batch")
+		return
+	}
+
+	status := make(map[string]int)
+	responses := make([]map[string]interface{}, 0, len(body.Requests))
+	for _, sub := range body.Requests {
+		out := map[string]interface{}{"id": sub.ID}
+
+		failedDep := false
+		for _, d := range sub.DependsOn {
+			st, ok := status[d]
+			if !ok {
+				writeError(w, http.StatusBadRequest, "invalidRequest", "dependsOn "+d+" tidak ada di batch")
+				return
+			}
+			failedDep = failedDep || st >= 400
+		}
+		if failedDep {
+			status[sub.ID] = http.StatusFailedDependency
+			out["status"] = http.StatusFailedDependency
+			responses = append(responses, out)
+			continue
+		}
+
+		req := httptest.NewRequest(sub.Method, "/v1.0"+sub.URL, strings.NewReader(string(sub.Body)))
+		req.Host = r.Host
+		req.Header.Set("Authorization", r.Header.Get("Authorization"))
+		for k, v := range sub.Headers {
+			req.Header.Set(k, v)
+		}
+		s.requests = append(s.requests, "$batch "+req.Method+" "+req.URL.Path)
+
+		rec := httptest.NewRecorder()
+		s.serve(rec, req)
+
+		status[sub.ID] = rec.Code
+		out["status"] = rec.Code
+		headers := map[string]string{}
+		for k := range rec.Header() {
+			headers[k] = rec.Header().Get(k)
+		}
+		out["headers"] = headers
+		if b := rec.Body.Bytes(); json.Valid(b) {
+			out["body"] = json.RawMessage(b)
+		}
+		responses = append(responses, out)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"responses": responses})
+}
+
+func permissionJSON(p *Permission) map[string]interface{} {
+	out := map[string]interface{}{
+		"id":    p.ID,
+		"roles": p.Roles,
+	}
+	grantee := map[string]interface{}{}
+	if p.GroupID != "" {
+		grantee["group"] = map[string]string{"id": p.GroupID}
+	} else {
+		grantee["user"] = map[string]string{"id": p.UserID, "email": p.Email}
+	}
+	out["grantedToV2"] = grantee
+	if p.Inherited {
+		out["inheritedFrom"] = map[string]string{"id": "parent"}
+	}
+	return out
+}
+
+// handleItem menangani /items/{id} (GET/PATCH) dan /items/{id}/{permissions[/{pid}] | invite | listItem[/fields]}.
+func (s *Server) handleItem(w http.ResponseWriter, r *http.Request, parts []string) {
+	it, ok := s.byID[parts[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "itemNotFound", "item tidak ditemukan")
+		return
+	}
+	sub := strings.Join(parts[1:], "/")
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.itemJSON(it, r.Host))
+
+	case sub == "" && r.Method == http.MethodPatch:
+		var body struct {
+			FileSystemInfo *struct {
+				LastModified time.Time `json:"lastModifiedDateTime"`
+			} `json:"fileSystemInfo"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+			return
+		}
+		if body.FileSystemInfo != nil {
+			it.Modified = body.FileSystemInfo.LastModified
+		}
+		writeJSON(w, http.StatusOK, s.itemJSON(it, r.Host))
+
+	case sub == "content" && r.Method == http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(it.Content)
+
+	case sub == "permissions" && r.Method == http.MethodGet:
+		list := make([]map[string]interface{}, 0, len(it.Permissions))
+		for _, p := range it.Permissions {
+			list = append(list, permissionJSON(p))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"value": list})
+
+	case strings.HasPrefix(sub, "permissions/"):
+		s.handlePermission(w, r, it, strings.TrimPrefix(sub, "permissions/"))
+
+	case sub == "invite" && r.Method == http.MethodPost:
+		var body struct {
+			Recipients []struct {
+				Email    string `json:"email"`
+				ObjectID string `json:"objectId"`
+			} `json:"recipients"`
+			Roles []string `json:"roles"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Recipients) == 0 {
+			writeError(w, http.StatusBadRequest, "invalidRequest", "recipients wajib diisi")
+			return
+		}
+		var created []map[string]interface{}
+		for _, rc := range body.Recipients {
+			p := &Permission{ID: s.nextID("perm"), Roles: body.Roles}
+			switch {
+			case rc.Email != "":
+				p.Email = strings.ToLower(rc.Email)
+				p.UserID = "user-" + p.Email
+			case strings.HasPrefix(rc.ObjectID, "user-"):
+				p.UserID = rc.ObjectID
+				p.Email = strings.TrimPrefix(rc.ObjectID, "user-")
+			default:
+				p.GroupID = rc.ObjectID
+			}
+			it.Permissions = append(it.Permissions, p)
+			created = append(created, permissionJSON(p))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"value": created})
+
+	case sub == "listItem" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]string{"id": strings.TrimPrefix(it.ID, "item-")})
+
+	case sub == "listItem/fields" && r.Method == http.MethodPatch:
+		fields := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			writeError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+			return
+		}
+		if it.Fields == nil {
+			it.Fields = map[string]interface{}{}
+		}
+		for k, v := range fields {
+			it.Fields[k] = v
+		}
+		// seperti SharePoint: PATCH kolom adalah edit biasa, Modified ikut berubah
+		it.Modified = time.Now()
+		writeJSON(w, http.StatusOK, it.Fields)
+
+	default:
+		writeError(w, http.StatusNotFound, "itemNotFound", "endpoint tidak didukung graphtest: "+r.URL.Path)
+	}
+}
+
+func (s *Server) handlePermission(w http.ResponseWriter, r *http.Request, it *Item, permID string) {
+	for i, p := range it.Permissions {
+		if p.ID != permID {
+			continue
+		}
+		if p.Inherited {
+			writeError(w, http.StatusBadRequest, "invalidRequest", "permission inherited tidak bisa diubah")
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			var body struct {
+				Roles []string `json:"roles"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+				return
+			}
+			p.Roles = body.Roles
+			writeJSON(w, http.StatusOK, permissionJSON(p))
+		case http.MethodDelete:
+			it.Permissions = append(it.Permissions[:i], it.Permissions[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "invalidRequest", "method tidak didukung")
+		}
+		return
+	}
+	writeError(w, http.StatusNotFound, "itemNotFound", "permission tidak ditemukan")
+}
+
+// handleListItemREST menangani SharePoint REST items({id})/ValidateUpdateListItem. Nilai form
+// disimpan ke Fields; Modified dibaca dengan format sharepoint.FieldDateValue. Tanpa Modified,
+// waktu modified menjadi waktu sekarang seperti di SharePoint.
+func (s *Server) handleListItemREST(w http.ResponseWriter, r *http.Request, rest string) {
+	listItemID, action, _ := strings.Cut(rest, ")/")
+	it, ok := s.byID["item-"+listItemID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "itemNotFound", "list item tidak ditemukan")
+		return
+	}
+	if action != "ValidateUpdateListItem" || r.Method != http.MethodPost {
+		writeError(w, http.StatusNotFound, "itemNotFound", "endpoint tidak didukung graphtest: "+r.URL.Path)
+		return
+	}
+
+	var body struct {
+		FormValues []struct {
+			FieldName  string `json:"FieldName"`
+			FieldValue string `json:"FieldValue"`
+		} `json:"formValues"`
+		NewDocumentUpdate bool `json:"bNewDocumentUpdate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+		return
+	}
+
+	modified := time.Now()
+	var results []map[string]interface{}
+	for _, fv := range body.FormValues {
+		res := map[string]interface{}{"FieldName": fv.FieldName, "FieldValue": fv.FieldValue, "HasException": false}
+		if fv.FieldName == "Modified" || fv.FieldName == "Created" {
+			t, err := sharepoint.ParseFieldDateValue(fv.FieldValue)
+			if err != nil {
+				res["HasException"] = true
+				res["ErrorMessage"] = "tanggal tidak valid: " + fv.FieldValue
+				results = append(results, res)
+				continue
+			}
+			if fv.FieldName == "Modified" {
+				modified = t
+			}
+		}
+		if it.Fields == nil {
+			it.Fields = map[string]interface{}{}
+		}
+		it.Fields[fv.FieldName] = fv.FieldValue
+		results = append(results, res)
+	}
+	it.Modified = modified
+	writeJSON(w, http.StatusOK, map[string]interface{}{"value": results})
+}
+
+type faultKey struct{}
+
+// withFault meneruskan fault Partial/Corrupt ke handler yang memprosesnya.
+func withFault(ctx context.Context, f *Fault) context.Context {
+	return context.WithValue(ctx, faultKey{}, f)
+}
+
+func faultFrom(ctx context.Context) *Fault {
+	f, _ := ctx.Value(faultKey{}).(*Fault)
+	return f
+}
@@ -0,0 +1,131 @@
+package sharepoint
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Group adalah security group Entra ID.
+type Group struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+// DirectoryUser adalah user Entra ID (atau anggota group).
+type DirectoryUser struct {
+	ID                string `json:"id"`
+	Mail              string `json:"mail"`
+	UserPrincipalName string `json:"userPrincipalName"`
+}
+
+// Email mengembalikan mail user (lowercase), atau UPN kalau mail kosong.
+func (u DirectoryUser) Email() string {
+	if u.Mail != "" {
+		return strings.ToLower(u.Mail)
+	}
+	return strings.ToLower(u.UserPrincipalName)
+}
+
+// odataQuote meng-escape string literal untuk $filter OData.
+func odataQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// mailNickname hanya boleh berisi huruf/angka ASCII tanpa spasi.
+func mailNickname(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 64 {
+		return b.String()[:64]
+	}
+	return b.String()
+}
+
+// FindGroup mencari security group berdasarkan displayName; nil kalau belum ada.
+func FindGroup(displayName string) (*Group, error) {
+	var result struct {
+		Value []Group `json:"value"`
+	}
+	path := "/groups?$select=id,displayName&$filter=" + url.QueryEscape("displayName eq "+odataQuote(displayName))
+	if err := graphGet(path, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Value) == 0 {
+		return nil, nil
+	}
+	if len(result.Value) > 1 {
+		return nil, fmt.Errorf("ada %d group bernama %q", len(result.Value), displayName)
+	}
+	return &result.Value[0], nil
+}
+
+// CreateSecurityGroup membuat security group Entra ID (bukan mail-enabled).
+func CreateSecurityGroup(displayName, description string) (*Group, error) {
+	body := map[string]interface{}{
+		"displayName":     displayName,
+		"description":     description,
+		"mailEnabled":     false,
+		"mailNickname":    mailNickname(displayName),
+		"securityEnabled": true,
+	}
+	var g Group
+	if err := graphSend("POST", "/groups", body, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// ListGroupMembers mengambil semua anggota user langsung dari group.
+func ListGroupMembers(groupID string) ([]DirectoryUser, error) {
+	var members []DirectoryUser
+	path := fmt.Sprintf("/groups/%s/members/microsoft.graph.user?$select=id,mail,userPrincipalName&$top=999", groupID)
+	for path != "" {
+		var page struct {
+			Value    []DirectoryUser `json:"value"`
+			NextLink string          `json:"@odata.nextLink"`
+		}
+		if err := graphGet(path, &page); err != nil {
+			return nil, err
+		}
+		members = append(members, page.Value...)
+		path = strings.TrimPrefix(page.NextLink, graphBaseURL())
+	}
+	return members, nil
+}
+
+// LookupUser mencari user Entra ID berdasarkan mail atau UPN; nil kalau tidak ada.
+func LookupUser(email string) (*DirectoryUser, error) {
+	var result struct {
+		Value []DirectoryUser `json:"value"`
+	}
+	filter := fmt.Sprintf("mail eq %s or userPrincipalName eq %s", odataQuote(email), odataQuote(email))
+	path := "/users?$select=id,mail,userPrincipalName&$filter=" + url.QueryEscape(filter)
+	if err := graphGet(path, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Value) == 0 {
+		return nil, nil
+	}
+	return &result.Value[0], nil
+}
+
+// AddGroupMember menambahkan user (directory object id) ke group.
+func AddGroupMember(groupID, userID string) error {
+	body := map[string]string{"@odata.id": graphBaseURL() + "/directoryObjects/" + userID}
+	return graphSend("POST", fmt.Sprintf("/groups/%s/members/$ref", groupID), body, nil)
+}
+
+// RemoveGroupMember mengeluarkan user dari group.
+func RemoveGroupMember(groupID, userID string) error {
+	return graphSend("DELETE", fmt.Sprintf("/groups/%s/members/%s/$ref", groupID, userID), nil, nil)
+}
+
+// InviteGroupToItem memberi role ke group Entra ID pada drive item.
+func InviteGroupToItem(itemID, groupID, role string) error {
+	return InviteObjectsToItem(itemID, []string{groupID}, role)
+}
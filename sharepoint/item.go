@@ -1,45 +1,137 @@
 package sharepoint
 
 import (
+	"converter_blob/pathmap"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strings"
+	"os"
+	"time"
 )
 
 type ItemResponse struct {
 	ID     string `json:"id"`
 	Name   string `json:"name"`
 	WebUrl string `json:"webUrl"`
+	Size   int64  `json:"size"`
+	File   *struct {
+		Hashes struct {
+			QuickXorHash string `json:"quickXorHash"`
+		} `json:"hashes"`
+	} `json:"file,omitempty"`
+	FileSystemInfo *struct {
+		LastModifiedDateTime time.Time `json:"lastModifiedDateTime"`
+	} `json:"fileSystemInfo,omitempty"`
+
+	// LocalQuickXorHash diisi UploadReaderAt: hash dari byte yang dikirim ke SharePoint.
+	LocalQuickXorHash string `json:"-"`
+	// Skipped berisi alasan kalau upload dilewati oleh ConflictSkip/ConflictNewer;
+	// item-nya adalah item yang sudah ada di SharePoint.
+	Skipped string `json:"-"`
+}
+
+// LastModified mengembalikan fileSystemInfo.lastModifiedDateTime, zero kalau tidak ada.
+func (i *ItemResponse) LastModified() time.Time {
+	if i == nil || i.FileSystemInfo == nil {
+		return time.Time{}
+	}
+	return i.FileSystemInfo.LastModifiedDateTime
+}
+
+// QuickXorHash mengembalikan file.hashes.quickXorHash, kosong untuk folder atau kalau belum dihitung.
+func (i *ItemResponse) QuickXorHash() string {
+	if i == nil || i.File == nil {
+		return ""
+	}
+	return i.File.Hashes.QuickXorHash
 }
 
 func GetItemIDFromPath(accessToken, siteID, path string) (*ItemResponse, error) {
-	// Encode spasi jadi %20, karena path perlu URL encoded
-	encodedPath := strings.ReplaceAll(path, " ", "%20")
-	url := fmt.Sprintf("https://graph.microsoft.com/v1.0/sites/%s/drive/root:/%s", siteID, encodedPath)
+	url := fmt.Sprintf("%s/sites/%s/drive/root:/%s", graphBaseURL(), siteID, pathmap.EscapePath(path))
 
-	req, err := http.NewRequest("GET", url, nil)
+	resp, err := client().R().
+		SetHeader("Authorization", "Bearer "+accessToken).
+		Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("❌ gagal membuat request: %w", err)
+		return nil, fmt.Errorf("❌ gagal melakukan request: %w", err)
+	}
+	if resp.IsError() {
+		return nil, responseError("ambil item "+path, resp)
 	}
 
-	req.Header.Add("Authorization", "Bearer "+accessToken)
+	var item ItemResponse
+	if err := json.Unmarshal(resp.Body(), &item); err != nil {
+		return nil, fmt.Errorf("❌ gagal decode response: %w", err)
+	}
+
+	return &item, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// GetDriveItem mengambil item di MS_DRIVE_ID berdasarkan path SharePoint (path yang sama dengan upload).
+func GetDriveItem(sharepointPath string) (*ItemResponse, error) {
+	token, err := GetToken()
 	if err != nil {
-		return nil, fmt.Errorf("❌ gagal melakukan request: %w", err)
+		return nil, err
+	}
+	driveID := os.Getenv("MS_DRIVE_ID")
+	if driveID == "" {
+		return nil, fmt.Errorf("❌ MS_DRIVE_ID belum diset")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("❌ response error (%d): %s", resp.StatusCode, string(body))
+	return getDriveItem(token, driveID, "root:/"+pathmap.EscapePath(sharepointPath))
+}
+
+// GetDriveWebURL mengembalikan URL library di belakang MS_DRIVE_ID (webUrl item root),
+// mis. https://tenant.sharepoint.com/sites/nama/Shared%20Documents.
+func GetDriveWebURL() (string, error) {
+	token, err := GetToken()
+	if err != nil {
+		return "", err
+	}
+	driveID := os.Getenv("MS_DRIVE_ID")
+	if driveID == "" {
+		return "", fmt.Errorf("❌ MS_DRIVE_ID belum diset")
+	}
+
+	root, err := getDriveItem(token, driveID, "root?$select=webUrl")
+	if err != nil {
+		return "", err
+	}
+	if root.WebUrl == "" {
+		return "", fmt.Errorf("webUrl drive %s kosong", driveID)
+	}
+	return root.WebUrl, nil
+}
+
+// DriveItemRequest adalah GetDriveItem sebagai sub-request batch; hasilnya di-Decode ke ItemResponse.
+func DriveItemRequest(id, sharepointPath string) BatchRequest {
+	return BatchRequest{ID: id, Method: "GET", URL: drivePathURL(sharepointPath)}
+}
+
+// drivePathURL adalah URL relatif item di MS_DRIVE_ID berdasarkan path; path kosong berarti root.
+func drivePathURL(sharepointPath string) string {
+	driveID := os.Getenv("MS_DRIVE_ID")
+	if p := pathmap.EscapePath(sharepointPath); p != "" {
+		return fmt.Sprintf("/drives/%s/root:/%s", driveID, p)
+	}
+	return fmt.Sprintf("/drives/%s/root", driveID)
+}
+
+// getDriveItem mengambil item di drive berdasarkan id atau path ("root:/a/b.pdf").
+func getDriveItem(token, driveID, ref string) (*ItemResponse, error) {
+	url := fmt.Sprintf("%s/drives/%s/%s", graphBaseURL(), driveID, ref)
+
+	resp, err := client().R().
+		SetHeader("Authorization", "Bearer "+token).
+		Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("❌ gagal melakukan request: %w", err)
+	}
+	if resp.IsError() {
+		return nil, responseError("ambil item "+ref, resp)
 	}
 
 	var item ItemResponse
-	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+	if err := json.Unmarshal(resp.Body(), &item); err != nil {
 		return nil, fmt.Errorf("❌ gagal decode response: %w", err)
 	}
 
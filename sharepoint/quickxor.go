@@ -0,0 +1,94 @@
+package sharepoint
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+)
+
+// QuickXorHash adalah hash yang dipakai OneDrive/SharePoint di file.hashes.quickXorHash:
+// setiap byte di-XOR ke register 160 bit yang berputar 11 bit per byte, lalu panjang data
+// di-XOR ke 8 byte terakhir. Hasilnya dibandingkan dalam bentuk base64.
+
+const (
+	quickXorWidth = 160
+	quickXorShift = 11
+	quickXorSize  = quickXorWidth / 8
+)
+
+type quickXor struct {
+	data       [3]uint64 // 64 + 64 + 32 bit
+	shiftSoFar int
+	length     int64
+}
+
+// NewQuickXorHash membuat hash.Hash QuickXorHash; data harus ditulis berurutan dari byte 0.
+func NewQuickXorHash() hash.Hash {
+	return &quickXor{}
+}
+
+func (q *quickXor) Write(p []byte) (int, error) {
+	cell := q.shiftSoFar / 64
+	offset := q.shiftSoFar % 64
+
+	iterations := min(len(p), quickXorWidth)
+	for i := 0; i < iterations; i++ {
+		isLast := cell == len(q.data)-1
+		bits := 64
+		if isLast {
+			bits = quickXorWidth % 64
+		}
+
+		// byte ke-i, i+160, i+320, ... jatuh di posisi bit yang sama
+		var x byte
+		for j := i; j < len(p); j += quickXorWidth {
+			x ^= p[j]
+		}
+
+		q.data[cell] ^= uint64(x) << uint(offset)
+		if offset > bits-8 {
+			next := 0
+			if !isLast {
+				next = cell + 1
+			}
+			q.data[next] ^= uint64(x) >> uint(bits-offset)
+		}
+
+		offset += quickXorShift
+		for offset >= bits {
+			if isLast {
+				cell = 0
+			} else {
+				cell++
+			}
+			offset -= bits
+		}
+	}
+
+	q.shiftSoFar = (q.shiftSoFar + quickXorShift*(len(p)%quickXorWidth)) % quickXorWidth
+	q.length += int64(len(p))
+	return len(p), nil
+}
+
+func (q *quickXor) Sum(b []byte) []byte {
+	var sum [quickXorSize]byte
+	binary.LittleEndian.PutUint64(sum[0:], q.data[0])
+	binary.LittleEndian.PutUint64(sum[8:], q.data[1])
+	binary.LittleEndian.PutUint32(sum[16:], uint32(q.data[2]))
+
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(q.length))
+	for i := range length {
+		sum[quickXorSize-8+i] ^= length[i]
+	}
+	return append(b, sum[:]...)
+}
+
+func (q *quickXor) Reset()         { *q = quickXor{} }
+func (q *quickXor) Size() int      { return quickXorSize }
+func (q *quickXor) BlockSize() int { return 64 }
+
+// QuickXorHashString mengembalikan hash dalam format base64 seperti di respons Graph.
+func QuickXorHashString(h hash.Hash) string {
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
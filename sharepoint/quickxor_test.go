@@ -0,0 +1,60 @@
+package sharepoint_test
+
+import (
+	"bytes"
+	"converter_blob/sharepoint"
+	"testing"
+)
+
+func sequence(n, mul, add int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*mul + add)
+	}
+	return b
+}
+
+// Nilai yang diharapkan dihitung dengan port Python dari implementasi referensi QuickXorHash C#
+// di dokumentasi OneDrive, bukan dari kode Go ini.
+var quickXorVectors = []struct {
+	name string
+	data []byte
+	want string
+}{
+	{"kosong", nil, "AAAAAAAAAAAAAAAAAAAAAAAAAAA="},
+	{"satu byte", []byte("a"), "YQAAAAAAAAAAAAAAAQAAAAAAAAA="},
+	{"abc", []byte("abc"), "YRDDGAAAAAAAAAAAAwAAAAAAAAA="},
+	{"kalimat", []byte("The quick brown fox jumps over the lazy dog"), "bMSlbysmxJL6S75XwfMcQZOpcr4="},
+	// lebih dari 160 byte: posisi kembali ke awal vektor
+	{"161 byte", sequence(161, 1, 0), "X+EGLlnQi0dVs5OErHWhEnz5wg4="},
+	{"1000 byte", sequence(1000, 7, 3), "dgD8j0n8sM0aPE5CUJ8tqmilX/E="},
+	// panjang ganjil dengan semua bit menyala: melintasi batas cell 64/32 bit
+	{"333 byte 0xff", bytes.Repeat([]byte{0xff}, 333), "//jHP/7xj3/84x//tcY//vEPAAA="},
+}
+
+func TestQuickXorHashVectors(t *testing.T) {
+	for _, tc := range quickXorVectors {
+		h := sharepoint.NewQuickXorHash()
+		h.Write(tc.data)
+		if got := sharepoint.QuickXorHashString(h); got != tc.want {
+			t.Errorf("%s: %s, seharusnya %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestQuickXorHashChunkedWrites(t *testing.T) {
+	// hasil harus sama berapa pun ukuran potongan Write (ganjil, >160, melintasi kelipatan 160)
+	for _, tc := range quickXorVectors {
+		for _, size := range []int{1, 3, 7, 159, 161, 320} {
+			h := sharepoint.NewQuickXorHash()
+			for rest := tc.data; len(rest) > 0; {
+				n := min(size, len(rest))
+				h.Write(rest[:n])
+				rest = rest[n:]
+			}
+			if got := sharepoint.QuickXorHashString(h); got != tc.want {
+				t.Errorf("%s per %d byte: %s, seharusnya %s", tc.name, size, got, tc.want)
+			}
+		}
+	}
+}
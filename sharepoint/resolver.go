@@ -0,0 +1,261 @@
+package sharepoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultUserCacheFile = "data/user_cache.json"
+	DefaultUserAliasFile = "data/user_aliases.json"
+
+	// DefaultUserCacheTTL adalah umur hasil resolve di cache sebelum dicek ulang ke Graph.
+	DefaultUserCacheTTL = 7 * 24 * time.Hour
+	// DefaultUserMissingTTL adalah umur hasil "tidak ditemukan" di cache; dibuat pendek supaya
+	// akun yang baru dibuat atau alias yang baru ditambahkan cepat terpakai.
+	DefaultUserMissingTTL = time.Hour
+)
+
+var objectIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ResolvedUser adalah hasil pemetaan email Teradocu ke user Entra ID. Di cache, ID kosong
+// berarti email tidak ditemukan di tenant saat ResolvedAt.
+type ResolvedUser struct {
+	Email             string    `json:"email"`
+	ID                string    `json:"id"`
+	Mail              string    `json:"mail,omitempty"`
+	UserPrincipalName string    `json:"user_principal_name,omitempty"`
+	Alias             string    `json:"alias,omitempty"` // target dari file alias, kalau ada
+	ResolvedAt        time.Time `json:"resolved_at"`
+}
+
+// Resolver memetakan email Teradocu ke object id Entra ID lewat Graph /users.
+// Hasil disimpan di file cache (yang tidak ditemukan juga, dengan umur lebih pendek); file
+// alias (JSON email → email/object id) dipakai untuk mailbox yang sudah berganti nama atau
+// email alias. Entri cache dicek ulang kalau sudah kedaluwarsa atau aliasnya berubah.
+type Resolver struct {
+	mu         sync.Mutex
+	cachePath  string
+	cache      map[string]ResolvedUser
+	aliases    map[string]string
+	missing    map[string]bool
+	dirty      bool
+	ttl        time.Duration
+	missingTTL time.Duration
+}
+
+// NewResolver membaca cache dan file alias. File yang belum ada dianggap kosong.
+func NewResolver(cachePath, aliasPath string) (*Resolver, error) {
+	r := &Resolver{
+		cachePath:  cachePath,
+		cache:      make(map[string]ResolvedUser),
+		aliases:    make(map[string]string),
+		missing:    make(map[string]bool),
+		ttl:        DefaultUserCacheTTL,
+		missingTTL: DefaultUserMissingTTL,
+	}
+
+	if err := readJSONFile(cachePath, &r.cache); err != nil {
+		return nil, fmt.Errorf("gagal membaca cache user %s: %w", cachePath, err)
+	}
+
+	var aliases map[string]string
+	if err := readJSONFile(aliasPath, &aliases); err != nil {
+		return nil, fmt.Errorf("gagal membaca alias user %s: %w", aliasPath, err)
+	}
+	for from, to := range aliases {
+		r.aliases[normalizeEmail(from)] = strings.TrimSpace(to)
+	}
+
+	return r, nil
+}
+
+// NewDefaultResolver memakai USER_CACHE_FILE dan USER_ALIAS_FILE, atau lokasi default di data/.
+// USER_CACHE_TTL (mis. "168h") mengganti DefaultUserCacheTTL.
+func NewDefaultResolver() (*Resolver, error) {
+	cachePath := os.Getenv("USER_CACHE_FILE")
+	if cachePath == "" {
+		cachePath = DefaultUserCacheFile
+	}
+	aliasPath := os.Getenv("USER_ALIAS_FILE")
+	if aliasPath == "" {
+		aliasPath = DefaultUserAliasFile
+	}
+	r, err := NewResolver(cachePath, aliasPath)
+	if err != nil {
+		return nil, err
+	}
+	if v := os.Getenv("USER_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("USER_CACHE_TTL tidak valid: %q", v)
+		}
+		r.ttl = ttl
+	}
+	return r, nil
+}
+
+func readJSONFile(path string, out interface{}) error {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// fresh bernilai true kalau entri cache masih boleh dipakai: dibuat dengan alias yang sama
+// dan belum melewati umurnya.
+func (r *Resolver) fresh(u ResolvedUser, alias string) bool {
+	if u.Alias != alias || u.ResolvedAt.IsZero() {
+		return false
+	}
+	ttl := r.ttl
+	if u.ID == "" {
+		ttl = r.missingTTL
+	}
+	return time.Since(u.ResolvedAt) < ttl
+}
+
+// Resolve mengembalikan user Entra ID untuk email, atau nil kalau tidak ada di tenant.
+func (r *Resolver) Resolve(email string) (*ResolvedUser, error) {
+	email = normalizeEmail(email)
+
+	r.mu.Lock()
+	if r.missing[email] {
+		r.mu.Unlock()
+		return nil, nil
+	}
+	alias := r.aliases[email]
+	if u, ok := r.cache[email]; ok && r.fresh(u, alias) {
+		if u.ID == "" {
+			r.missing[email] = true
+			r.mu.Unlock()
+			return nil, nil
+		}
+		r.mu.Unlock()
+		return &u, nil
+	}
+	r.mu.Unlock()
+
+	var (
+		found *DirectoryUser
+		err   error
+	)
+	switch {
+	case alias != "" && objectIDPattern.MatchString(alias):
+		found, err = getUserByID(alias)
+	case alias != "":
+		found, err = LookupUser(alias)
+	default:
+		found, err = LookupUser(email)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if found == nil {
+		r.missing[email] = true
+		r.cache[email] = ResolvedUser{Email: email, Alias: alias, ResolvedAt: time.Now()}
+		r.dirty = true
+		return nil, nil
+	}
+
+	u := ResolvedUser{
+		Email:             email,
+		ID:                found.ID,
+		Mail:              found.Mail,
+		UserPrincipalName: found.UserPrincipalName,
+		Alias:             alias,
+		ResolvedAt:        time.Now(),
+	}
+	r.cache[email] = u
+	r.dirty = true
+	return &u, nil
+}
+
+// ResolveAll memetakan semua email; email yang tidak ada di tenant dikembalikan di missing.
+func (r *Resolver) ResolveAll(emails []string) (map[string]ResolvedUser, []string, error) {
+	resolved := make(map[string]ResolvedUser)
+	var missing []string
+
+	for _, e := range emails {
+		u, err := r.Resolve(e)
+		if err != nil {
+			return resolved, missing, fmt.Errorf("gagal resolve %s: %w", e, err)
+		}
+		if u == nil {
+			missing = append(missing, normalizeEmail(e))
+			continue
+		}
+		resolved[u.Email] = *u
+	}
+
+	sort.Strings(missing)
+	return resolved, missing, nil
+}
+
+// Missing mengembalikan email yang sudah dicek dan tidak ditemukan di tenant.
+func (r *Resolver) Missing() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]string, 0, len(r.missing))
+	for e := range r.missing {
+		list = append(list, e)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Save menulis cache ke disk (tmp + rename) kalau ada hasil baru.
+func (r *Resolver) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.cachePath), os.ModePerm); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(r.cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.cachePath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, r.cachePath); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
+func getUserByID(id string) (*DirectoryUser, error) {
+	var u DirectoryUser
+	if err := graphGet("/users/"+id+"?$select=id,mail,userPrincipalName", &u); err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
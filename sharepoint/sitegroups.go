@@ -0,0 +1,169 @@
+package sharepoint
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// SiteGroup adalah SharePoint site group di SP_SITE_URL.
+type SiteGroup struct {
+	ID    int    `json:"Id"`
+	Title string `json:"Title"`
+}
+
+// SiteUser adalah anggota SharePoint site group.
+type SiteUser struct {
+	ID        int    `json:"Id"`
+	Email     string `json:"Email"`
+	LoginName string `json:"LoginName"`
+}
+
+// roleDefinitionTypes memetakan role Graph ke RoleTypeKind SharePoint
+// (Reader, Contributor, Administrator/Full Control).
+var roleDefinitionTypes = map[string]int{
+	"read":  2,
+	"write": 3,
+	"owner": 5,
+}
+
+var (
+	roleDefinitionMu  sync.Mutex
+	roleDefinitionIDs = make(map[string]int)
+)
+
+// FindSiteGroup mencari site group berdasarkan nama; nil kalau belum ada.
+func FindSiteGroup(title string) (*SiteGroup, error) {
+	base, err := siteURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value []SiteGroup `json:"value"`
+	}
+	filter := url.QueryEscape("Title eq " + odataQuote(title))
+	if err := spRest("GET", base+"/_api/web/sitegroups?$select=Id,Title&$filter="+filter, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Value) == 0 {
+		return nil, nil
+	}
+	return &result.Value[0], nil
+}
+
+// CreateSiteGroup membuat site group baru.
+func CreateSiteGroup(title, description string) (*SiteGroup, error) {
+	base, err := siteURL()
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]string{"Title": title, "Description": description}
+	var g SiteGroup
+	if err := spRest("POST", base+"/_api/web/sitegroups", body, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// ListSiteGroupUsers mengambil anggota site group.
+func ListSiteGroupUsers(groupID int) ([]SiteUser, error) {
+	base, err := siteURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value []SiteUser `json:"value"`
+	}
+	endpoint := fmt.Sprintf("%s/_api/web/sitegroups/getbyid(%d)/users?$select=Id,Email,LoginName", base, groupID)
+	if err := spRest("GET", endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Value, nil
+}
+
+// AddSiteGroupUser menambahkan user (berdasarkan email/UPN) ke site group.
+func AddSiteGroupUser(groupID int, email string) error {
+	base, err := siteURL()
+	if err != nil {
+		return err
+	}
+
+	body := map[string]string{"LoginName": "i:0#.f|membership|" + email}
+	return spRest("POST", fmt.Sprintf("%s/_api/web/sitegroups/getbyid(%d)/users", base, groupID), body, nil)
+}
+
+// RemoveSiteGroupUser mengeluarkan user dari site group.
+func RemoveSiteGroupUser(groupID, userID int) error {
+	base, err := siteURL()
+	if err != nil {
+		return err
+	}
+
+	return spRest("POST", fmt.Sprintf("%s/_api/web/sitegroups/getbyid(%d)/users/removebyid(%d)", base, groupID, userID), nil, nil)
+}
+
+// roleDefinitionID mengambil id role definition SharePoint untuk role Graph (di-cache).
+func roleDefinitionID(role string) (int, error) {
+	kind, ok := roleDefinitionTypes[role]
+	if !ok {
+		return 0, fmt.Errorf("role %q tidak punya role definition SharePoint", role)
+	}
+
+	roleDefinitionMu.Lock()
+	defer roleDefinitionMu.Unlock()
+	if id, ok := roleDefinitionIDs[role]; ok {
+		return id, nil
+	}
+
+	base, err := siteURL()
+	if err != nil {
+		return 0, err
+	}
+	var out struct {
+		ID int `json:"Id"`
+	}
+	if err := spRest("GET", fmt.Sprintf("%s/_api/web/roledefinitions/getbytype(%d)?$select=Id", base, kind), nil, &out); err != nil {
+		return 0, err
+	}
+	roleDefinitionIDs[role] = out.ID
+	return out.ID, nil
+}
+
+// SetSiteGroupItemRole memberi site group tepat satu role di drive item: role assignment lama
+// untuk group itu dihapus dulu lalu role baru ditambahkan. Item harus sudah putus inheritance.
+func SetSiteGroupItemRole(itemID string, groupID int, role string) error {
+	roleID, err := roleDefinitionID(role)
+	if err != nil {
+		return err
+	}
+	itemURL, err := spListItemURL(itemID)
+	if err != nil {
+		return err
+	}
+
+	// 404 berarti group belum punya role assignment di item ini
+	err = spRest("POST", fmt.Sprintf("%s/roleassignments/getbyprincipalid(%d)/deleteobject()", itemURL, groupID), nil, nil)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+
+	return spRest("POST", fmt.Sprintf("%s/roleassignments/addroleassignment(principalid=%d,roledefid=%d)", itemURL, groupID, roleID), nil, nil)
+}
+
+// RemoveSiteGroupItemRole mencabut semua role site group di drive item; 404 berarti group
+// memang tidak punya role assignment di item itu.
+func RemoveSiteGroupItemRole(itemID string, groupID int) error {
+	itemURL, err := spListItemURL(itemID)
+	if err != nil {
+		return err
+	}
+
+	err = spRest("POST", fmt.Sprintf("%s/roleassignments/getbyprincipalid(%d)/deleteobject()", itemURL, groupID), nil, nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,204 @@
+package sharepoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Graph v1.0 tidak punya API untuk memutus inheritance permission, jadi bagian ini memakai
+// SharePoint REST (_api) di SP_SITE_URL. SharePoint REST menolak token app-only berbasis
+// client secret; pakai sertifikat kalau tenant memblokirnya.
+
+var (
+	driveListID   string
+	driveListOnce sync.Once
+	driveListErr  error
+)
+
+func siteURL() (string, error) {
+	u := strings.TrimRight(os.Getenv("SP_SITE_URL"), "/")
+	if u == "" {
+		return "", fmt.Errorf("❌ SP_SITE_URL belum diset (mis. https://tenant.sharepoint.com/sites/nama)")
+	}
+	return u, nil
+}
+
+func spRestToken() (string, error) {
+	u, err := siteURL()
+	if err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", fmt.Errorf("SP_SITE_URL tidak valid: %w", err)
+	}
+
+	token, err := getTokenForScope("https://" + parsed.Host + "/.default")
+	if err != nil {
+		return "", fmt.Errorf("token SharePoint REST: %w", err)
+	}
+	return token, nil
+}
+
+// getDriveListID mengambil id list dokumen di belakang MS_DRIVE_ID.
+func getDriveListID() (string, error) {
+	driveListOnce.Do(func() {
+		var out struct {
+			ID string `json:"id"`
+		}
+		driveListErr = graphGet(fmt.Sprintf("/drives/%s/list?$select=id", os.Getenv("MS_DRIVE_ID")), &out)
+		driveListID = out.ID
+	})
+	return driveListID, driveListErr
+}
+
+// getListItemID mengambil id list item (integer SharePoint) untuk drive item.
+func getListItemID(itemID string) (string, error) {
+	var out struct {
+		ID string `json:"id"`
+	}
+	err := graphGet(fmt.Sprintf("/drives/%s/items/%s/listItem?$select=id", os.Getenv("MS_DRIVE_ID"), itemID), &out)
+	return out.ID, err
+}
+
+// spListItemURL membentuk URL REST list item untuk drive item.
+func spListItemURL(itemID string) (string, error) {
+	base, err := siteURL()
+	if err != nil {
+		return "", err
+	}
+	listID, err := getDriveListID()
+	if err != nil {
+		return "", err
+	}
+	listItemID, err := getListItemID(itemID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/_api/web/lists(guid'%s')/items(%s)", base, listID, listItemID), nil
+}
+
+// spRest memanggil SharePoint REST (URL absolut) dengan JSON nometadata dan decode respons ke out (boleh nil).
+func spRest(method, endpoint string, body, out interface{}) error {
+	token, err := spRestToken()
+	if err != nil {
+		return err
+	}
+
+	req := client().R().
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Accept", "application/json;odata=nometadata")
+	if body != nil {
+		req = req.SetHeader("Content-Type", "application/json;odata=nometadata").SetBody(body)
+	}
+
+	resp, err := req.Execute(method, endpoint)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return responseError(method+" "+endpoint, resp)
+	}
+	if out == nil || len(resp.Body()) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Body(), out)
+}
+
+// HasUniquePermissions mengecek apakah item sudah tidak mewarisi permission dari parent.
+func HasUniquePermissions(itemID string) (bool, error) {
+	itemURL, err := spListItemURL(itemID)
+	if err != nil {
+		return false, err
+	}
+
+	var out struct {
+		HasUniqueRoleAssignments bool `json:"HasUniqueRoleAssignments"`
+	}
+	if err := spRest("GET", itemURL+"?$select=HasUniqueRoleAssignments", nil, &out); err != nil {
+		return false, err
+	}
+	return out.HasUniqueRoleAssignments, nil
+}
+
+// BreakInheritance memutus pewarisan permission item dengan menyalin role dari parent, jadi
+// Owners/Members/Visitors site dan grant lain di parent tetap ada; grant salinan yang tidak
+// seharusnya ada dicabut oleh pemanggil. Permission sendiri di subfolder tidak direset.
+// Mengembalikan false kalau item memang sudah punya permission sendiri.
+func BreakInheritance(itemID string) (bool, error) {
+	unique, err := HasUniquePermissions(itemID)
+	if err != nil {
+		return false, err
+	}
+	if unique {
+		return false, nil
+	}
+
+	itemURL, err := spListItemURL(itemID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := spRest("POST", itemURL+"/breakroleinheritance(copyRoleAssignments=true,clearSubscopes=false)", nil, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateItemValues mengisi kolom list item (hasil FieldMapping.Build) lewat ValidateUpdateListItem
+// dengan bNewDocumentUpdate, jadi tidak ada versi baru dan kolom Person sistem (Author/Editor)
+// bisa diisi. Modified/Created ikut dikirim dengan modified/created (kalau tidak nol) supaya
+// tanggal asli dari fileSystemInfo saat upload tidak berganti menjadi waktu update.
+func UpdateItemValues(itemID string, values map[string]string, created, modified time.Time) error {
+	type formValue struct {
+		FieldName  string `json:"FieldName"`
+		FieldValue string `json:"FieldValue"`
+	}
+	var form []formValue
+	for field, value := range values {
+		form = append(form, formValue{FieldName: field, FieldValue: value})
+	}
+	if len(form) == 0 {
+		return nil
+	}
+	if !created.IsZero() {
+		form = append(form, formValue{FieldName: "Created", FieldValue: FieldDateValue(created)})
+	}
+	if !modified.IsZero() {
+		form = append(form, formValue{FieldName: "Modified", FieldValue: FieldDateValue(modified)})
+	}
+
+	itemURL, err := spListItemURL(itemID)
+	if err != nil {
+		return err
+	}
+
+	var out struct {
+		Value []struct {
+			FieldName    string `json:"FieldName"`
+			ErrorMessage string `json:"ErrorMessage"`
+			HasException bool   `json:"HasException"`
+		} `json:"value"`
+	}
+	body := map[string]interface{}{"formValues": form, "bNewDocumentUpdate": true}
+	if err := spRest("POST", itemURL+"/ValidateUpdateListItem", body, &out); err != nil {
+		return err
+	}
+
+	// error per kolom datang dengan status 200
+	var errs []string
+	for _, v := range out.Value {
+		if v.HasException {
+			errs = append(errs, fmt.Sprintf("%s: %s", v.FieldName, v.ErrorMessage))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("ValidateUpdateListItem: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
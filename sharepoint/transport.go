@@ -0,0 +1,161 @@
+package sharepoint
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Semua request Graph/SharePoint dari proses ini lewat graphTransport, jadi batas laju dan
+// konkurensi berlaku bersama untuk semua worker:
+//   - token bucket GRAPH_RATE_LIMIT request/detik (default 20),
+//   - konkurensi maksimum GRAPH_MAX_CONCURRENCY (default 16) yang dipotong setengah setiap
+//     kali kena 429/503 lalu naik lagi satu per satu setelah request sukses berturut-turut,
+//   - jeda global selama Retry-After sebelum request berikutnya dikirim.
+
+const (
+	defaultGraphRateLimit   = 20
+	defaultGraphConcurrency = 16
+	// jumlah respons sukses berturut-turut sebelum batas konkurensi dinaikkan satu
+	concurrencyRecoverAfter = 50
+	defaultThrottleCooldown = 10 * time.Second
+)
+
+type graphTransport struct {
+	base http.RoundTripper
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int
+	max      int
+	inFlight int
+	streak   int
+	pause    time.Time // tidak ada request baru sebelum waktu ini
+
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+func newGraphTransport(base http.RoundTripper) *graphTransport {
+	max := envInt("GRAPH_MAX_CONCURRENCY", defaultGraphConcurrency)
+	rate := float64(envInt("GRAPH_RATE_LIMIT", defaultGraphRateLimit))
+
+	t := &graphTransport{
+		base:   base,
+		limit:  max,
+		max:    max,
+		rate:   rate,
+		tokens: rate,
+		last:   time.Now(),
+	}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// isTokenRequest: request token ke endpoint login AAD tidak ikut dibatasi.
+func isTokenRequest(req *http.Request) bool {
+	return strings.HasSuffix(req.URL.Path, "/oauth2/v2.0/token")
+}
+
+func (t *graphTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isTokenRequest(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	t.acquire()
+	resp, err := t.base.RoundTrip(req)
+	t.release(resp)
+
+	return resp, err
+}
+
+// acquire menunggu jeda throttling, slot konkurensi, dan token rate limit.
+func (t *graphTransport) acquire() {
+	t.mu.Lock()
+	for t.inFlight >= t.limit {
+		t.cond.Wait()
+	}
+	t.inFlight++
+
+	for {
+		now := time.Now()
+		if now.Before(t.pause) {
+			wait := t.pause.Sub(now)
+			t.mu.Unlock()
+			time.Sleep(wait)
+			t.mu.Lock()
+			continue
+		}
+
+		t.tokens = min(t.rate, t.tokens+now.Sub(t.last).Seconds()*t.rate)
+		t.last = now
+		if t.tokens >= 1 {
+			t.tokens--
+			break
+		}
+		wait := time.Duration((1 - t.tokens) / t.rate * float64(time.Second))
+		t.mu.Unlock()
+		time.Sleep(wait)
+		t.mu.Lock()
+	}
+	t.mu.Unlock()
+}
+
+// release mengembalikan slot dan menyesuaikan konkurensi berdasarkan status respons.
+func (t *graphTransport) release(resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+	defer t.cond.Broadcast()
+
+	if resp == nil {
+		return
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		wait := newGraphError("", resp.StatusCode, resp.Header, nil).RetryAfter
+		if wait <= 0 {
+			wait = defaultThrottleCooldown
+		}
+		if until := time.Now().Add(wait); until.After(t.pause) {
+			t.pause = until
+		}
+		t.streak = 0
+		if t.limit > 1 {
+			t.limit = max(1, t.limit/2)
+			log.Printf("⏳ Graph throttling (%d): konkurensi turun ke %d, jeda %s\n", resp.StatusCode, t.limit, wait)
+		}
+		return
+	}
+
+	if resp.StatusCode < 400 && t.limit < t.max {
+		t.streak++
+		if t.streak >= concurrencyRecoverAfter {
+			t.streak = 0
+			t.limit++
+		}
+	}
+}
+
+// retryAfter dipakai resty: tunggu sesuai header Retry-After kalau ada, selain itu backoff default.
+func retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	if resp == nil || resp.RawResponse == nil {
+		return 0, nil
+	}
+	return newGraphError("", resp.StatusCode(), resp.Header(), nil).RetryAfter, nil
+}
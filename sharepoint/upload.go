@@ -1,14 +1,14 @@
 package sharepoint
 
 import (
-	"bytes"
+	"converter_blob/pathmap"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/url"
+	"log"
+	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -18,10 +18,6 @@ import (
 var (
 	httpClient *resty.Client
 	once       sync.Once
-
-	cachedToken string
-	tokenExpiry time.Time
-	tokenMu     sync.Mutex
 )
 
 // ================= INIT =================
@@ -31,6 +27,7 @@ func client() *resty.Client {
 	once.Do(func() {
 
 		httpClient = resty.New().
+			SetTransport(newGraphTransport(http.DefaultTransport)).
 			SetTimeout(30 * time.Minute).
 			AddRetryCondition(func(r *resty.Response, err error) bool {
 
@@ -48,90 +45,13 @@ func client() *resty.Client {
 			}).
 			SetRetryCount(5).
 			SetRetryWaitTime(3 * time.Second).
-			SetRetryMaxWaitTime(30 * time.Second)
+			SetRetryMaxWaitTime(2 * time.Minute).
+			SetRetryAfter(retryAfter)
 	})
 
 	return httpClient
 }
 
-// ================= TOKEN CACHE =================
-
-func GetToken() string {
-
-	tokenMu.Lock()
-	defer tokenMu.Unlock()
-
-	if cachedToken != "" && time.Now().Before(tokenExpiry) {
-		return cachedToken
-	}
-
-	c := client()
-
-	tokenResp := struct {
-		Token     string `json:"access_token"`
-		ExpiresIn int    `json:"expires_in"`
-	}{}
-
-	form := map[string]string{
-		"grant_type":    "client_credentials",
-		"client_id":     os.Getenv("MS_CLIENT_ID"),
-		"client_secret": os.Getenv("MS_CLIENT_SECRET"),
-		"scope":         "https://graph.microsoft.com/.default",
-	}
-
-	resp, err := c.R().
-		SetFormData(form).
-		SetResult(&tokenResp).
-		Post("https://login.microsoftonline.com/" +
-			os.Getenv("MS_TENANT_ID") +
-			"/oauth2/v2.0/token")
-
-	if err != nil || resp.IsError() {
-		return ""
-	}
-
-	cachedToken = tokenResp.Token
-
-	// refresh sebelum expire
-	tokenExpiry = time.Now().Add(
-		time.Duration(tokenResp.ExpiresIn-300) * time.Second,
-	)
-
-	return cachedToken
-}
-
-// ================= SANITIZE =================
-
-func sanitizeSPName(name string) string {
-
-	invalid := []string{
-		"#", "%", "&", "{", "}", "\\", "<", ">", "*",
-		"?", "/", "$", "!", "'", "\"", ":", "@", "+",
-	}
-
-	for _, c := range invalid {
-		name = strings.ReplaceAll(name, c, "_")
-	}
-
-	return name
-}
-
-func escapePath(p string) string {
-
-	p = filepath.ToSlash(p)
-	p = strings.TrimPrefix(p, "/")
-
-	parts := strings.Split(p, "/")
-
-	for i := range parts {
-		parts[i] = url.PathEscape(
-			sanitizeSPName(parts[i]),
-		)
-	}
-
-	return strings.Join(parts, "/")
-}
-
 // ================= TYPES =================
 
 type uploadSessionResp struct {
@@ -145,70 +65,135 @@ type uploadState struct {
 	FilePath  string `json:"filePath"`
 }
 
+// UploadOptions mengatur upload session untuk UploadReaderAt.
+type UploadOptions struct {
+	// StateFile menyimpan uploadUrl supaya upload bisa dilanjutkan; kosong berarti tanpa resume.
+	StateFile string
+	// StateKey harus sama dengan saat state disimpan agar session lama dipakai ulang.
+	StateKey string
+	// ModifiedAt diisi ke fileSystemInfo.lastModifiedDateTime supaya tanggal asli ikut terbawa.
+	ModifiedAt time.Time
+	// CreatedAt diisi ke fileSystemInfo.createdDateTime (kolom Created tidak bisa di-PATCH).
+	CreatedAt time.Time
+	// Conflict menentukan perlakuan kalau item sudah ada; kosong berarti ConflictReplace.
+	Conflict ConflictPolicy
+}
+
 // ================= MAIN UPLOAD =================
 
 func UploadFileChunkedResumeV2(localPath, sharepointPath string) (string, error) {
 
-	token := GetToken()
-	driveID := os.Getenv("MS_DRIVE_ID")
-
-	if token == "" || driveID == "" {
-		return "", fmt.Errorf("❌ Token atau MS_DRIVE_ID belum diset")
+	if _, err := UploadFile(localPath, sharepointPath, UploadOptions{}); err != nil {
+		return "", err
 	}
 
+	return sharepointPath, nil
+}
+
+// UploadFile meng-upload file lokal dengan state resume di <localPath>.uploadstate.
+func UploadFile(localPath, sharepointPath string, opts UploadOptions) (*ItemResponse, error) {
+
 	// open file
 	f, err := os.Open(localPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer f.Close()
 
 	fi, err := f.Stat()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	fileSize := fi.Size()
+	opts.StateFile = localPath + ".uploadstate"
+	opts.StateKey = localPath
+
+	return UploadReaderAt(f, fi.Size(), sharepointPath, opts)
+}
+
+// UploadReaderAt meng-upload size byte dari r ke sharepointPath: file sampai 4MB dengan satu
+// PUT /content, file lebih besar lewat upload session per chunk.
+// r bisa berupa file lokal maupun sumber lain (mis. large object PostgreSQL) karena hanya ReadAt yang dipakai.
+// Setelah selesai, QuickXorHash byte yang dikirim dibandingkan dengan hash drive item; kalau beda
+// item tetap dikembalikan bersama *HashMismatchError. Dengan ConflictSkip/ConflictNewer upload
+// bisa dilewati: item lama dikembalikan dengan Skipped terisi.
+func UploadReaderAt(r io.ReaderAt, size int64, sharepointPath string, opts UploadOptions) (*ItemResponse, error) {
+
+	token, err := GetToken()
+	if err != nil {
+		return nil, err
+	}
+	driveID := os.Getenv("MS_DRIVE_ID")
+
+	if driveID == "" {
+		return nil, fmt.Errorf("❌ MS_DRIVE_ID belum diset")
+	}
+
+	fileSize := size
 
 	if fileSize == 0 {
-		return "", fmt.Errorf("file kosong")
+		return nil, fmt.Errorf("file kosong")
 	}
 
-	escapedPath := escapePath(sharepointPath)
+	escapedPath := pathmap.EscapePath(sharepointPath)
 
 	c := client()
 
-	stateFile := localPath + ".uploadstate"
+	stateFile := opts.StateFile
 	var uploadURL string
 
 	// ================= RESUME STATE =================
 
-	if b, err := os.ReadFile(stateFile); err == nil {
+	if stateFile != "" {
+		if b, err := os.ReadFile(stateFile); err == nil {
 
-		var st uploadState
+			var st uploadState
 
-		if json.Unmarshal(b, &st) == nil &&
-			st.FilePath == localPath {
+			if json.Unmarshal(b, &st) == nil &&
+				st.FilePath == opts.StateKey {
 
-			uploadURL = st.UploadURL
+				uploadURL = st.UploadURL
+			}
 		}
 	}
 
 	// ================= CREATE SESSION =================
 
+	if uploadURL == "" && (opts.Conflict == ConflictSkip || opts.Conflict == ConflictNewer) {
+		existing, err := checkExisting(token, driveID, escapedPath, r, fileSize, opts)
+		if err != nil {
+			return nil, fmt.Errorf("cek item %s: %w", sharepointPath, err)
+		}
+		if existing != nil {
+			return existing, nil
+		}
+	}
+
+	// file kecil cukup satu PUT /content, tanpa session dan state resume
+	if uploadURL == "" && fileSize <= simpleUploadLimit {
+		return uploadSimple(token, driveID, escapedPath, sharepointPath, r, fileSize, opts)
+	}
+
 	if uploadURL == "" {
 
 		createURL := fmt.Sprintf(
-			"https://graph.microsoft.com/v1.0/drives/%s/root:/%s:/createUploadSession",
+			"%s/drives/%s/root:/%s:/createUploadSession",
+			graphBaseURL(),
 			driveID,
 			escapedPath,
 		)
 
+		item := map[string]interface{}{
+			"@microsoft.graph.conflictBehavior": opts.Conflict.conflictBehavior(),
+			"name":                              filepath.Base(escapedPath),
+		}
+
+		if info := opts.fileSystemInfo(); info != nil {
+			item["fileSystemInfo"] = info
+		}
+
 		body := map[string]interface{}{
-			"item": map[string]interface{}{
-				"@microsoft.graph.conflictBehavior": "replace",
-				"name":                              filepath.Base(escapedPath),
-			},
+			"item": item,
 		}
 
 		resp, err := c.R().
@@ -218,33 +203,40 @@ func UploadFileChunkedResumeV2(localPath, sharepointPath string) (string, error)
 			Post(createURL)
 
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		if resp.IsError() {
-			return "", fmt.Errorf("create session gagal: %s",
-				resp.String())
+			return nil, responseError("create session "+sharepointPath, resp)
 		}
 
 		var s uploadSessionResp
 
 		if err := json.Unmarshal(resp.Body(), &s); err != nil {
-			return "", err
+			return nil, err
 		}
 
 		if s.UploadURL == "" {
-			return "", fmt.Errorf("uploadURL kosong")
+			return nil, fmt.Errorf("uploadURL kosong")
 		}
 
 		uploadURL = s.UploadURL
 
-		save := uploadState{
-			UploadURL: uploadURL,
-			FilePath:  localPath,
+		if stateFile != "" {
+			save := uploadState{
+				UploadURL: uploadURL,
+				FilePath:  opts.StateKey,
+			}
+
+			if b, err := json.Marshal(save); err == nil {
+				_ = os.WriteFile(stateFile, b, 0644)
+			}
 		}
+	}
 
-		if b, err := json.Marshal(save); err == nil {
-			_ = os.WriteFile(stateFile, b, 0644)
+	removeState := func() {
+		if stateFile != "" {
+			_ = os.Remove(stateFile)
 		}
 	}
 
@@ -282,6 +274,29 @@ func UploadFileChunkedResumeV2(localPath, sharepointPath string) (string, error)
 
 	buf := make([]byte, chunkSize)
 
+	// QuickXorHash dihitung dari byte yang dikirim, berurutan dari byte 0. Kalau session
+	// di-resume, bagian yang sudah diterima server dibaca ulang dulu untuk hash saja.
+	qx := NewQuickXorHash()
+	var hashed int64
+
+	hashUpTo := func(to int64) error {
+		for hashed < to {
+			n, err := r.ReadAt(buf[:min(chunkSize, to-hashed)], hashed)
+			if n == 0 && err != nil {
+				return err
+			}
+			qx.Write(buf[:n])
+			hashed += int64(n)
+		}
+		return nil
+	}
+
+	if err := hashUpTo(start); err != nil {
+		return nil, err
+	}
+
+	var item *ItemResponse
+
 	for start < fileSize {
 
 		remaining := fileSize - start
@@ -291,13 +306,21 @@ func UploadFileChunkedResumeV2(localPath, sharepointPath string) (string, error)
 			readLen = remaining
 		}
 
-		n, err := f.ReadAt(buf[:readLen], start)
+		n, err := r.ReadAt(buf[:readLen], start)
 		if err != nil && err != io.EOF {
-			return "", err
+			return nil, err
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("sumber berakhir di byte %d dari %d", start, fileSize)
 		}
 
 		end := start + int64(n) - 1
 
+		if start <= hashed && end >= hashed {
+			qx.Write(buf[hashed-start : n])
+			hashed = end + 1
+		}
+
 		contentRange := fmt.Sprintf(
 			"bytes %d-%d/%d",
 			start,
@@ -309,20 +332,21 @@ func UploadFileChunkedResumeV2(localPath, sharepointPath string) (string, error)
 			SetHeader("Authorization", "Bearer "+token).
 			SetHeader("Content-Length", fmt.Sprintf("%d", n)).
 			SetHeader("Content-Range", contentRange).
-			SetBody(bytes.NewReader(buf[:n])).
+			// []byte, bukan reader: retry resty mengirim ulang body utuh
+			SetBody(buf[:n]).
 			Put(uploadURL)
 
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		// success
 		if resp.StatusCode() == 200 ||
 			resp.StatusCode() == 201 {
 
-			_ = os.Remove(stateFile)
-
-			return sharepointPath, nil
+			item = &ItemResponse{}
+			_ = json.Unmarshal(resp.Body(), item)
+			break
 		}
 
 		// partial
@@ -338,6 +362,9 @@ func UploadFileChunkedResumeV2(localPath, sharepointPath string) (string, error)
 				var next int64
 				fmt.Sscanf(s.NextExpectedRanges[0], "%d-", &next)
 
+				if err := hashUpTo(next); err != nil {
+					return nil, err
+				}
 				start = next
 				continue
 			}
@@ -346,21 +373,129 @@ func UploadFileChunkedResumeV2(localPath, sharepointPath string) (string, error)
 			continue
 		}
 
+		ge := responseError("upload "+sharepointPath, resp)
+
 		// throttling
-		if resp.StatusCode() == 429 {
+		if IsThrottled(ge) {
 
-			time.Sleep(10 * time.Second)
+			wait := ge.RetryAfter
+			if wait <= 0 {
+				wait = 10 * time.Second
+			}
+			time.Sleep(wait)
 			continue
 		}
 
-		return "", fmt.Errorf(
-			"upload gagal status %d: %s",
-			resp.StatusCode(),
-			resp.String(),
-		)
+		return nil, ge
 	}
 
-	_ = os.Remove(stateFile)
+	removeState()
 
-	return sharepointPath, nil
+	if err := hashUpTo(fileSize); err != nil {
+		return nil, err
+	}
+
+	return verifyUploadedItem(token, driveID, escapedPath, sharepointPath, item, QuickXorHashString(qx))
+}
+
+// simpleUploadLimit adalah batas ukuran PUT /content di Graph; di atasnya wajib upload session.
+const simpleUploadLimit = 4 * 1024 * 1024
+
+// fileSystemInfo adalah tanggal asli untuk drive item, nil kalau keduanya tidak diketahui.
+func (opts UploadOptions) fileSystemInfo() map[string]interface{} {
+	info := make(map[string]interface{})
+	if !opts.CreatedAt.IsZero() {
+		info["createdDateTime"] = opts.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if !opts.ModifiedAt.IsZero() {
+		info["lastModifiedDateTime"] = opts.ModifiedAt.UTC().Format(time.RFC3339)
+	}
+	if len(info) == 0 {
+		return nil
+	}
+	return info
+}
+
+// uploadSimple meng-upload file kecil dengan satu PUT /content. Tanggal asli tidak bisa
+// dikirim bersama konten, jadi diset lewat PATCH fileSystemInfo sesudahnya kalau ada; kalau
+// PATCH gagal upload tetap dianggap berhasil.
+func uploadSimple(token, driveID, escapedPath, sharepointPath string, r io.ReaderAt, size int64, opts UploadOptions) (*ItemResponse, error) {
+
+	buf := make([]byte, size)
+	if n, err := r.ReadAt(buf, 0); int64(n) != size {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("sumber berakhir di byte %d dari %d: %w", n, size, err)
+	}
+
+	qx := NewQuickXorHash()
+	qx.Write(buf)
+
+	c := client()
+
+	resp, err := c.R().
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/octet-stream").
+		SetQueryParam("@microsoft.graph.conflictBehavior", opts.Conflict.conflictBehavior()).
+		SetBody(buf).
+		Put(fmt.Sprintf("%s/drives/%s/root:/%s:/content", graphBaseURL(), driveID, escapedPath))
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, responseError("upload "+sharepointPath, resp)
+	}
+
+	item := &ItemResponse{}
+	_ = json.Unmarshal(resp.Body(), item)
+
+	if info := opts.fileSystemInfo(); info != nil && item.ID != "" {
+		resp, err := c.R().
+			SetHeader("Authorization", "Bearer "+token).
+			SetHeader("Content-Type", "application/json").
+			SetBody(map[string]interface{}{"fileSystemInfo": info}).
+			Patch(fmt.Sprintf("%s/drives/%s/items/%s", graphBaseURL(), driveID, item.ID))
+		// konten sudah tersimpan; tanggal yang gagal diset tidak membuat upload diulang
+		switch {
+		case err != nil:
+			log.Printf("⚠️ Gagal set tanggal file %s: %v\n", sharepointPath, err)
+		case resp.IsError():
+			log.Printf("⚠️ %v\n", responseError("set tanggal file "+sharepointPath, resp))
+		default:
+			_ = json.Unmarshal(resp.Body(), item)
+		}
+	}
+
+	return verifyUploadedItem(token, driveID, escapedPath, sharepointPath, item, QuickXorHashString(qx))
+}
+
+// verifyUploadedItem membandingkan QuickXorHash lokal dengan file.hashes di drive item hasil upload.
+// Kalau respons upload tidak membawa hash (atau tidak ada respons akhir), item diambil ulang.
+func verifyUploadedItem(token, driveID, escapedPath, sharepointPath string, item *ItemResponse, localHash string) (*ItemResponse, error) {
+
+	if item.QuickXorHash() == "" {
+		ref := "root:/" + escapedPath
+		if item != nil && item.ID != "" {
+			ref = "items/" + item.ID
+		}
+		fetched, err := getDriveItem(token, driveID, ref)
+		if err != nil {
+			return nil, fmt.Errorf("verifikasi hash %s: %w", sharepointPath, err)
+		}
+		item = fetched
+	}
+
+	item.LocalQuickXorHash = localHash
+
+	remote := item.QuickXorHash()
+	if remote == "" {
+		log.Printf("⚠️ SharePoint tidak mengembalikan quickXorHash untuk %s, verifikasi hash dilewati\n", sharepointPath)
+		return item, nil
+	}
+	if remote != localHash {
+		return item, &HashMismatchError{Path: sharepointPath, Local: localHash, Remote: remote}
+	}
+
+	return item, nil
 }
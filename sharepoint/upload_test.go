@@ -0,0 +1,173 @@
+package sharepoint_test
+
+import (
+	"bytes"
+	"converter_blob/sharepoint"
+	"converter_blob/sharepoint/graphtest"
+	"crypto/rand"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newServer menjalankan graphtest dan mengarahkan package sharepoint ke server itu.
+func newServer(t *testing.T) *graphtest.Server {
+	t.Helper()
+	srv := graphtest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.Use()
+	return srv
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// countRequests menghitung request di log graphtest yang diawali prefix ("METHOD path").
+func countRequests(reqs []string, prefix string) int {
+	n := 0
+	for _, r := range reqs {
+		if strings.HasPrefix(r, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestUploadReaderAtSimplePut(t *testing.T) {
+	srv := newServer(t)
+	data := randomBytes(t, 4*1024*1024)
+
+	item, err := sharepoint.UploadReaderAt(bytes.NewReader(data), int64(len(data)), "Test/kecil.pdf", sharepoint.UploadOptions{})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	stored, ok := srv.Item("Test/kecil.pdf")
+	if !ok || !bytes.Equal(stored.Content, data) {
+		t.Fatal("isi file di server tidak sama dengan data yang di-upload")
+	}
+	if item.QuickXorHash() == "" || item.QuickXorHash() != item.LocalQuickXorHash {
+		t.Fatalf("quickXorHash lokal %q, SharePoint %q", item.LocalQuickXorHash, item.QuickXorHash())
+	}
+
+	reqs := srv.Requests()
+	if n := countRequests(reqs, "POST /v1.0/drives/"+graphtest.DriveID+"/root:/Test/kecil.pdf:/createUploadSession"); n != 0 {
+		t.Errorf("file 4MB tidak boleh membuat upload session, dapat %d", n)
+	}
+	if n := countRequests(reqs, "PUT /v1.0/drives/"+graphtest.DriveID+"/root:/Test/kecil.pdf:/content"); n != 1 {
+		t.Errorf("PUT /content dikirim %d kali, seharusnya 1", n)
+	}
+}
+
+func TestUploadReaderAtThrottled(t *testing.T) {
+	srv := newServer(t)
+	data := randomBytes(t, 21*1024*1024)
+
+	srv.AddFault(graphtest.Fault{Method: http.MethodPost, Path: "createUploadSession", Status: http.StatusServiceUnavailable, RetryAfter: 1})
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Status: http.StatusTooManyRequests, RetryAfter: 1})
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Status: http.StatusServiceUnavailable, RetryAfter: 1})
+
+	item, err := sharepoint.UploadReaderAt(bytes.NewReader(data), int64(len(data)), "Test/throttle.pdf", sharepoint.UploadOptions{})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if stored, ok := srv.Item("Test/throttle.pdf"); !ok || !bytes.Equal(stored.Content, data) {
+		t.Fatal("isi file di server tidak sama dengan data yang di-upload")
+	}
+	if item.Size != int64(len(data)) {
+		t.Errorf("ukuran item %d, seharusnya %d", item.Size, len(data))
+	}
+
+	reqs := srv.Requests()
+	if n := countRequests(reqs, "POST /v1.0/drives/"+graphtest.DriveID+"/root:/Test/throttle.pdf:/createUploadSession"); n != 2 {
+		t.Errorf("createUploadSession dikirim %d kali, seharusnya 2 (sekali di-503)", n)
+	}
+	// 3 chunk + 2 chunk yang di-throttle
+	if n := countRequests(reqs, "PUT /upload/"); n != 5 {
+		t.Errorf("PUT chunk dikirim %d kali, seharusnya 5", n)
+	}
+}
+
+func TestUploadReaderAtPartialRange(t *testing.T) {
+	srv := newServer(t)
+	data := randomBytes(t, 20*1024*1024)
+
+	// server hanya menerima separuh chunk pertama; upload harus lanjut dari nextExpectedRanges
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Partial: true})
+
+	item, err := sharepoint.UploadReaderAt(bytes.NewReader(data), int64(len(data)), "Test/partial.pdf", sharepoint.UploadOptions{})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if stored, ok := srv.Item("Test/partial.pdf"); !ok || !bytes.Equal(stored.Content, data) {
+		t.Fatal("isi file di server tidak sama dengan data yang di-upload")
+	}
+	if item.QuickXorHash() != item.LocalQuickXorHash {
+		t.Fatalf("quickXorHash lokal %q, SharePoint %q", item.LocalQuickXorHash, item.QuickXorHash())
+	}
+	// tanpa fault cukup 2 chunk; dengan fault 0-5MB, 5-15MB, 15-20MB
+	if n := countRequests(srv.Requests(), "PUT /upload/"); n != 3 {
+		t.Errorf("PUT chunk dikirim %d kali, seharusnya 3", n)
+	}
+}
+
+func TestUploadReaderAtResume(t *testing.T) {
+	srv := newServer(t)
+	data := randomBytes(t, 21*1024*1024)
+	opts := sharepoint.UploadOptions{
+		StateFile: filepath.Join(t.TempDir(), "resume.uploadstate"),
+		StateKey:  "resume",
+	}
+
+	// chunk pertama diterima separuh, chunk berikutnya gagal 500: upload berhenti dengan session tersimpan
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Partial: true})
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Status: http.StatusInternalServerError})
+	if _, err := sharepoint.UploadReaderAt(bytes.NewReader(data), int64(len(data)), "Test/resume.pdf", opts); err == nil {
+		t.Fatal("upload dengan 500 seharusnya gagal")
+	}
+	if _, ok := srv.Item("Test/resume.pdf"); ok {
+		t.Fatal("item tidak boleh ada sebelum upload selesai")
+	}
+
+	before := len(srv.Requests())
+	item, err := sharepoint.UploadReaderAt(bytes.NewReader(data), int64(len(data)), "Test/resume.pdf", opts)
+	if err != nil {
+		t.Fatalf("resume upload: %v", err)
+	}
+	if stored, ok := srv.Item("Test/resume.pdf"); !ok || !bytes.Equal(stored.Content, data) {
+		t.Fatal("isi file hasil resume tidak sama dengan data yang di-upload")
+	}
+	if item.QuickXorHash() != item.LocalQuickXorHash {
+		t.Fatalf("quickXorHash lokal %q, SharePoint %q", item.LocalQuickXorHash, item.QuickXorHash())
+	}
+
+	reqs := srv.Requests()[before:]
+	if n := countRequests(reqs, "POST /v1.0/drives/"+graphtest.DriveID+"/root:/Test/resume.pdf:/createUploadSession"); n != 0 {
+		t.Errorf("resume tidak boleh membuat session baru, dapat %d createUploadSession", n)
+	}
+	if n := countRequests(reqs, "GET /upload/"); n != 1 {
+		t.Errorf("resume seharusnya membaca nextExpectedRanges sekali, dapat %d", n)
+	}
+}
+
+func TestUploadReaderAtHashMismatch(t *testing.T) {
+	srv := newServer(t)
+	data := randomBytes(t, 21*1024*1024)
+
+	srv.AddFault(graphtest.Fault{Method: http.MethodPut, Path: "/upload/", Corrupt: true})
+
+	item, err := sharepoint.UploadReaderAt(bytes.NewReader(data), int64(len(data)), "Test/rusak.pdf", sharepoint.UploadOptions{})
+	if !sharepoint.IsHashMismatch(err) {
+		t.Fatalf("upload rusak seharusnya HashMismatchError, dapat %v", err)
+	}
+	if item == nil || item.QuickXorHash() == "" || item.QuickXorHash() == item.LocalQuickXorHash {
+		t.Fatalf("item seharusnya dikembalikan dengan hash berbeda, dapat %+v", item)
+	}
+}
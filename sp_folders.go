@@ -0,0 +1,141 @@
+package main
+
+import (
+	"converter_blob/database"
+	"converter_blob/sharepoint"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const spFoldersFile = "data/sp_folders.json"
+
+// spFolder adalah folder Teradocu beserta drive item id-nya di SharePoint, dipakai ulang oleh
+// langkah permission dan metadata tanpa lookup per path.
+type spFolder struct {
+	FolderID  string    `json:"folder_id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Path      string    `json:"path"`
+	SPPath    string    `json:"sp_path"`
+	ItemID    string    `json:"item_id"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// loadSPFolders membaca data/sp_folders.json (key: folder id Teradocu); file yang belum ada berarti kosong.
+func loadSPFolders() (map[string]spFolder, error) {
+	folders := make(map[string]spFolder)
+	b, err := os.ReadFile(spFoldersFile)
+	if os.IsNotExist(err) {
+		return folders, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &folders); err != nil {
+		return nil, fmt.Errorf("gagal decode %s: %w", spFoldersFile, err)
+	}
+	return folders, nil
+}
+
+func saveSPFolders(folders map[string]spFolder) error {
+	if err := os.MkdirAll(filepath.Dir(spFoldersFile), os.ModePerm); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(folders, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := spFoldersFile + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, spFoldersFile)
+}
+
+// createSharePointFolders membuat seluruh hierarki teradocu.folder (yang tidak dihapus dan
+// cocok dengan pathFilter) di MS_DRIVE_ID, termasuk folder kosong, dengan nama dari pathmap.
+// Aman dijalankan ulang: folder yang sudah ada hanya di-lookup. Drive item id dicatat ke
+// data/sp_folders.json.
+func createSharePointFolders(db *sql.DB, pathFilter string) error {
+	rows, err := database.GetFolderList(db, pathFilter)
+	if err != nil {
+		return err
+	}
+
+	var list []spFolder
+	for rows.Next() {
+		var f spFolder
+		var parentID sql.NullString
+		if err := rows.Scan(&f.FolderID, &parentID, &f.Path); err != nil {
+			fmt.Println("❌ Gagal membaca hasil query:", err)
+			continue
+		}
+		f.ParentID = parentID.String
+		list = append(list, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	log.Printf("📁 %d folder Teradocu akan dibuat di SharePoint\n", len(list))
+
+	saved, err := loadSPFolders()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(list))
+	for i := range list {
+		list[i].SPPath = spPaths.MapFolder(list[i].Path)
+		paths = append(paths, list[i].SPPath)
+	}
+
+	startTime := time.Now()
+	ids, failed, err := sharepoint.EnsureFolders(paths)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	now := time.Now()
+	for _, f := range list {
+		p := sharepoint.CleanFolderPath(f.SPPath)
+		itemID, ok := ids[p]
+		if !ok {
+			cause := failed[p]
+			if cause == nil {
+				cause = fmt.Errorf("parent gagal dibuat")
+			}
+			problems = append(problems, fmt.Sprintf("%s\t%v", f.Path, cause))
+			continue
+		}
+		if prev, ok := saved[f.FolderID]; ok && prev.ItemID != itemID {
+			log.Printf("⚠️ Drive item id %s berubah: %s → %s\n", f.Path, prev.ItemID, itemID)
+		}
+		f.ItemID = itemID
+		f.CheckedAt = now
+		saved[f.FolderID] = f
+	}
+
+	if err := saveSPFolders(saved); err != nil {
+		return fmt.Errorf("gagal menyimpan %s: %w", spFoldersFile, err)
+	}
+
+	log.Printf("✅ Folder SharePoint siap: %d, gagal: %d (%s), id tersimpan di %s\n",
+		len(list)-len(problems), len(problems), time.Since(startTime), spFoldersFile)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	for _, line := range problems {
+		log.Println("❌ " + strings.ReplaceAll(line, "\t", " "))
+	}
+	return fmt.Errorf("%d folder gagal dibuat", len(problems))
+}
@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// AccessExportSchemaVersion naik setiap kali struktur AccessExport berubah.
+const AccessExportSchemaVersion = 1
+
+// AccessScope mencatat sumber data yang dipakai untuk membangun AccessExport.
+type AccessScope struct {
+	Profiles []string `json:"profiles,omitempty"`
+	FolderID string   `json:"folder_id,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	All      bool     `json:"all,omitempty"`
+}
+
+type FolderAccessEntry struct {
+	FolderId    string        `json:"folder_id"`
+	FolderPath  string        `json:"folder_path"`
+	EmailAccess []EmailAccess `json:"email_access"`
+}
+
+// AccessExport adalah isi users.json: peta akses folder Teradocu per email.
+type AccessExport struct {
+	SchemaVersion int                 `json:"schema_version"`
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Scope         AccessScope         `json:"scope"`
+	Folders       []FolderAccessEntry `json:"folders"`
+}
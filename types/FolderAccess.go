@@ -18,6 +18,7 @@ type UserFolderAccess struct {
 }
 
 type FolderRoleAccess struct {
-	FolderRole     string `json:"folder_role"`
-	RolePermission string `json:"role_permission"`
+	FolderRole      string `json:"folder_role"`
+	RolePermission  string `json:"role_permission"`
+	SharepointGroup string `json:"sharepoint_group,omitempty"` // site group penerima, kosong = per user
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"converter_blob/manifest"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// localRecord adalah checksum terakhir yang tercatat di manifest untuk satu file lokal.
+type localRecord struct {
+	runID string
+	entry manifest.Entry
+	// file boleh hilang kalau sudah di-upload pada run dengan --delete-after-upload
+	deleted bool
+}
+
+// loadLocalRecords membaca semua manifest run dan mengambil entry terbaru per local_path
+// yang punya SHA-256 (dummy dan --direct-sp tidak punya file lokal).
+func loadLocalRecords() (map[string]localRecord, error) {
+	runs, err := manifest.ListRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]localRecord)
+	for _, runID := range runs {
+		info, err := manifest.LoadInfo(runID)
+		if err != nil {
+			log.Printf("⚠️ %v\n", err)
+			continue
+		}
+		store, err := manifest.Open(runID)
+		if err != nil {
+			return nil, err
+		}
+		entries := store.Entries()
+		store.Close()
+
+		for _, e := range entries {
+			if e.LocalPath == "" || e.SHA256 == "" {
+				continue
+			}
+			p := filepath.Clean(e.LocalPath)
+			if prev, ok := records[p]; ok && prev.entry.UpdatedAt.After(e.UpdatedAt) {
+				continue
+			}
+			records[p] = localRecord{
+				runID:   runID,
+				entry:   e,
+				deleted: info.DeleteAfterUpload && e.IsUploaded(),
+			}
+		}
+	}
+	return records, nil
+}
+
+// hashFile menghitung ulang SHA-256 (dan QuickXorHash kalau quickXor) file lokal.
+func hashFile(path string, quickXor bool) (int64, fileChecksums, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fileChecksums{}, err
+	}
+	defer f.Close()
+
+	h := newChecksumWriter(quickXor)
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return n, fileChecksums{}, err
+	}
+	return n, h.sums(), nil
+}
+
+// verifyLocal menghitung ulang hash semua file di exportDir dan membandingkannya dengan
+// checksum saat ekstraksi. File yang lebih kecil dilaporkan terpotong, yang isinya beda
+// dilaporkan berubah; file yang tercatat tapi tidak ada dilaporkan hilang.
+func verifyLocal(exportDir string) error {
+	records, err := loadLocalRecords()
+	if err != nil {
+		return fmt.Errorf("gagal membaca manifest: %w", err)
+	}
+	log.Printf("🔍 %d file tercatat dengan checksum di manifest\n", len(records))
+
+	var (
+		problems  []string
+		ok        int
+		untracked int
+		seen      = make(map[string]bool)
+	)
+
+	err = filepath.WalkDir(exportDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ERROR\t%s\t%v", path, err))
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(path, ".part") {
+			problems = append(problems, fmt.Sprintf("SISA_PART\t%s\tekstraksi tidak selesai", path))
+			return nil
+		}
+
+		p := filepath.Clean(path)
+		rec, tracked := records[p]
+		if !tracked {
+			untracked++
+			return nil
+		}
+		seen[p] = true
+
+		size, sums, err := hashFile(p, rec.entry.QuickXorHash != "")
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ERROR\t%s\t%v", p, err))
+			return nil
+		}
+
+		switch {
+		case size < rec.entry.SizeBytes:
+			problems = append(problems, fmt.Sprintf("TERPOTONG\t%s\t%d dari %d byte (run %s)", p, size, rec.entry.SizeBytes, rec.runID))
+		case sums.sha256 != rec.entry.SHA256:
+			problems = append(problems, fmt.Sprintf("BERUBAH\t%s\tsha256 %s != %s (run %s)", p, sums.sha256, rec.entry.SHA256, rec.runID))
+		case rec.entry.QuickXorHash != "" && sums.quickXor != rec.entry.QuickXorHash:
+			problems = append(problems, fmt.Sprintf("BERUBAH\t%s\tquickXorHash %s != %s (run %s)", p, sums.quickXor, rec.entry.QuickXorHash, rec.runID))
+		default:
+			ok++
+		}
+
+		if checked := ok + len(problems); checked%500 == 0 {
+			log.Printf("⏳ %d file diperiksa\n", checked)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for p, rec := range records {
+		if !seen[p] && !rec.deleted {
+			missing = append(missing, fmt.Sprintf("HILANG\t%s\ttercatat di run %s", p, rec.runID))
+		}
+	}
+	sort.Strings(missing)
+	problems = append(problems, missing...)
+
+	log.Printf("✅ Cocok: %d | ❌ Bermasalah: %d | ❔ Tidak tercatat di manifest: %d\n", ok, len(problems), untracked)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	for _, line := range problems {
+		log.Println("❌ " + strings.ReplaceAll(line, "\t", " "))
+	}
+
+	if err := os.MkdirAll("logs", os.ModePerm); err != nil {
+		return err
+	}
+	reportPath := fmt.Sprintf("logs/verify_local_%s.txt", time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(reportPath, []byte(strings.Join(problems, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	return fmt.Errorf("%d file bermasalah, laporan di %s", len(problems), reportPath)
+}